@@ -0,0 +1,196 @@
+package keystore
+
+import (
+	"context"
+	"crypto/ed25519"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"sync"
+
+	"github.com/fsnotify/fsnotify"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+// Distributor publishes this node's public key to, and fetches a peer's
+// public key from, some out-of-band channel. Nodes never exchange private
+// keys; ReconcilePeers/createStartupConfig only ever deal in PublicKey.
+type Distributor interface {
+	// Publish makes nodeName's public key available to other nodes.
+	Publish(ctx context.Context, nodeName string, pub ed25519.PublicKey) error
+	// Fetch returns peerName's public key, if it has been published yet.
+	Fetch(ctx context.Context, peerName string) (pub ed25519.PublicKey, ok bool, err error)
+}
+
+// secretKeyDataField is the Secret data key each node's public key is stored
+// under, PEM-encoded.
+const secretKeyDataField = "ed25519.pub"
+
+// SecretDistributor exchanges keys through Kubernetes Secrets: one Secret
+// per node, named "<secretPrefix><nodeName>", in a configurable namespace.
+type SecretDistributor struct {
+	Client       kubernetes.Interface
+	Namespace    string
+	SecretPrefix string
+}
+
+// NewSecretDistributor builds a SecretDistributor. secretPrefix defaults to
+// "tinc-key-" if empty.
+func NewSecretDistributor(client kubernetes.Interface, namespace, secretPrefix string) *SecretDistributor {
+	if secretPrefix == "" {
+		secretPrefix = "tinc-key-"
+	}
+	return &SecretDistributor{Client: client, Namespace: namespace, SecretPrefix: secretPrefix}
+}
+
+func (d *SecretDistributor) secretName(nodeName string) string {
+	return d.SecretPrefix + nodeName
+}
+
+// Publish creates or updates the Secret holding nodeName's public key.
+func (d *SecretDistributor) Publish(ctx context.Context, nodeName string, pub ed25519.PublicKey) error {
+	secrets := d.Client.CoreV1().Secrets(d.Namespace)
+
+	secret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: d.secretName(nodeName)},
+		Data:       map[string][]byte{secretKeyDataField: []byte(EncodePublicKey(pub))},
+	}
+
+	if _, err := secrets.Create(ctx, secret, metav1.CreateOptions{}); err != nil {
+		if !apierrors.IsAlreadyExists(err) {
+			return fmt.Errorf("creating secret %q: %v", secret.Name, err)
+		}
+		if _, err := secrets.Update(ctx, secret, metav1.UpdateOptions{}); err != nil {
+			return fmt.Errorf("updating secret %q: %v", secret.Name, err)
+		}
+	}
+
+	return nil
+}
+
+// Fetch reads peerName's public key from its Secret, if it exists yet.
+func (d *SecretDistributor) Fetch(ctx context.Context, peerName string) (ed25519.PublicKey, bool, error) {
+	secret, err := d.Client.CoreV1().Secrets(d.Namespace).Get(ctx, d.secretName(peerName), metav1.GetOptions{})
+	if err != nil {
+		if apierrors.IsNotFound(err) {
+			return nil, false, nil
+		}
+		return nil, false, fmt.Errorf("getting secret for peer %q: %v", peerName, err)
+	}
+
+	pub, err := DecodePublicKey(string(secret.Data[secretKeyDataField]))
+	if err != nil {
+		return nil, false, fmt.Errorf("decoding public key for peer %q: %v", peerName, err)
+	}
+
+	return pub, true, nil
+}
+
+// FileDropDistributor exchanges keys by watching a directory for files named
+// after each peer, each holding that peer's PEM-encoded public key. It's the
+// fallback for deployments with no Kubernetes API to publish Secrets to.
+type FileDropDistributor struct {
+	Dir string
+
+	mu   sync.RWMutex
+	keys map[string]ed25519.PublicKey
+
+	watcher *fsnotify.Watcher
+}
+
+// NewFileDropDistributor starts watching dir for peer host files and returns
+// once the initial directory listing has been loaded.
+func NewFileDropDistributor(dir string) (*FileDropDistributor, error) {
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return nil, fmt.Errorf("creating fsnotify watcher: %v", err)
+	}
+	if err := watcher.Add(dir); err != nil {
+		watcher.Close()
+		return nil, fmt.Errorf("watching %s: %v", dir, err)
+	}
+
+	d := &FileDropDistributor{Dir: dir, keys: make(map[string]ed25519.PublicKey), watcher: watcher}
+
+	if err := d.loadAll(); err != nil {
+		watcher.Close()
+		return nil, err
+	}
+
+	go d.watch()
+
+	return d, nil
+}
+
+func (d *FileDropDistributor) loadAll() error {
+	entries, err := ioutil.ReadDir(d.Dir)
+	if err != nil {
+		return fmt.Errorf("reading %s: %v", d.Dir, err)
+	}
+
+	for _, entry := range entries {
+		if entry.IsDir() {
+			continue
+		}
+		d.loadFile(filepath.Join(d.Dir, entry.Name()))
+	}
+
+	return nil
+}
+
+func (d *FileDropDistributor) loadFile(path string) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return
+	}
+
+	pub, err := DecodePublicKey(string(data))
+	if err != nil {
+		return
+	}
+
+	d.mu.Lock()
+	d.keys[filepath.Base(path)] = pub
+	d.mu.Unlock()
+}
+
+func (d *FileDropDistributor) watch() {
+	for event := range d.watcher.Events {
+		if event.Op&(fsnotify.Create|fsnotify.Write) != 0 {
+			d.loadFile(event.Name)
+		}
+	}
+}
+
+// Close stops watching the drop directory.
+func (d *FileDropDistributor) Close() error {
+	return d.watcher.Close()
+}
+
+// Publish writes nodeName's public key into the drop directory so other
+// nodes watching the same directory (e.g. over a shared volume) pick it up.
+func (d *FileDropDistributor) Publish(ctx context.Context, nodeName string, pub ed25519.PublicKey) error {
+	path := filepath.Join(d.Dir, nodeName)
+	if err := ioutil.WriteFile(path, []byte(EncodePublicKey(pub)), 0644); err != nil {
+		return fmt.Errorf("writing public key for %q: %v", nodeName, err)
+	}
+
+	d.mu.Lock()
+	d.keys[nodeName] = pub
+	d.mu.Unlock()
+
+	return nil
+}
+
+// Fetch returns peerName's public key if it has been seen in the drop
+// directory yet.
+func (d *FileDropDistributor) Fetch(ctx context.Context, peerName string) (ed25519.PublicKey, bool, error) {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+
+	pub, ok := d.keys[peerName]
+	return pub, ok, nil
+}
@@ -0,0 +1,164 @@
+// Package keystore generates and persists the Ed25519 keypair a tinc node
+// authenticates itself with, and encodes/decodes keys in the PEM-like format
+// tinc's own host files use.
+package keystore
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/base64"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+const (
+	privateKeyFile = "ed25519_key.priv"
+	publicKeyFile  = "ed25519_key.pub"
+
+	privateKeyHeader = "-----BEGIN ED25519 PRIVATE KEY-----"
+	privateKeyFooter = "-----END ED25519 PRIVATE KEY-----"
+	publicKeyHeader  = "-----BEGIN ED25519 PUBLIC KEY-----"
+	publicKeyFooter  = "-----END ED25519 PUBLIC KEY-----"
+)
+
+// KeyPair is the Ed25519 identity a tinc node presents to its peers.
+type KeyPair struct {
+	PublicKey  ed25519.PublicKey
+	PrivateKey ed25519.PrivateKey
+}
+
+// EnsureKeyPair loads the keypair persisted under dir, generating and
+// persisting a new one if none exists yet. dir is created if necessary.
+func EnsureKeyPair(dir string) (*KeyPair, error) {
+	kp, err := Load(dir)
+	if err == nil {
+		return kp, nil
+	}
+	if !os.IsNotExist(err) {
+		return nil, err
+	}
+
+	kp, err = Generate()
+	if err != nil {
+		return nil, err
+	}
+
+	if err := kp.Save(dir); err != nil {
+		return nil, err
+	}
+
+	return kp, nil
+}
+
+// Generate creates a new random Ed25519 keypair.
+func Generate() (*KeyPair, error) {
+	pub, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, fmt.Errorf("generating ed25519 keypair: %v", err)
+	}
+	return &KeyPair{PublicKey: pub, PrivateKey: priv}, nil
+}
+
+// Load reads a previously persisted keypair from dir.
+func Load(dir string) (*KeyPair, error) {
+	privData, err := ioutil.ReadFile(filepath.Join(dir, privateKeyFile))
+	if err != nil {
+		return nil, err
+	}
+	pubData, err := ioutil.ReadFile(filepath.Join(dir, publicKeyFile))
+	if err != nil {
+		return nil, err
+	}
+
+	priv, err := DecodePrivateKey(string(privData))
+	if err != nil {
+		return nil, fmt.Errorf("decoding %s: %v", filepath.Join(dir, privateKeyFile), err)
+	}
+	pub, err := DecodePublicKey(string(pubData))
+	if err != nil {
+		return nil, fmt.Errorf("decoding %s: %v", filepath.Join(dir, publicKeyFile), err)
+	}
+
+	return &KeyPair{PublicKey: pub, PrivateKey: priv}, nil
+}
+
+// Save persists the keypair under dir, creating it if necessary.
+func (kp *KeyPair) Save(dir string) error {
+	if err := os.MkdirAll(dir, os.FileMode(0700)); err != nil {
+		return err
+	}
+
+	if err := ioutil.WriteFile(filepath.Join(dir, privateKeyFile), []byte(EncodePrivateKey(kp.PrivateKey)), os.FileMode(0600)); err != nil {
+		return err
+	}
+	if err := ioutil.WriteFile(filepath.Join(dir, publicKeyFile), []byte(EncodePublicKey(kp.PublicKey)), os.FileMode(0644)); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// PublicKeyBase64 renders a public key as the bare base64 body used inside
+// an "add <Name>.Ed25519PublicKey = ..." line, without the BEGIN/END banner.
+func PublicKeyBase64(pub ed25519.PublicKey) string {
+	return base64.StdEncoding.EncodeToString(pub)
+}
+
+// EncodePublicKey renders a public key in tinc's host-file format: a single
+// base64 line wrapped in a BEGIN/END banner.
+func EncodePublicKey(pub ed25519.PublicKey) string {
+	return encodeBlock(publicKeyHeader, publicKeyFooter, pub)
+}
+
+// EncodePrivateKey renders a private key in the same banner format, for the
+// node's own key file.
+func EncodePrivateKey(priv ed25519.PrivateKey) string {
+	return encodeBlock(privateKeyHeader, privateKeyFooter, priv)
+}
+
+func encodeBlock(header, footer string, data []byte) string {
+	return fmt.Sprintf("%s\n%s\n%s\n", header, base64.StdEncoding.EncodeToString(data), footer)
+}
+
+// DecodePublicKey parses a public key previously rendered by EncodePublicKey
+// (or received from a peer in the same format).
+func DecodePublicKey(s string) (ed25519.PublicKey, error) {
+	data, err := decodeBlock(s, publicKeyHeader, publicKeyFooter)
+	if err != nil {
+		return nil, err
+	}
+	if len(data) != ed25519.PublicKeySize {
+		return nil, fmt.Errorf("unexpected ed25519 public key size %d", len(data))
+	}
+	return ed25519.PublicKey(data), nil
+}
+
+// DecodePrivateKey parses a private key previously rendered by
+// EncodePrivateKey.
+func DecodePrivateKey(s string) (ed25519.PrivateKey, error) {
+	data, err := decodeBlock(s, privateKeyHeader, privateKeyFooter)
+	if err != nil {
+		return nil, err
+	}
+	if len(data) != ed25519.PrivateKeySize {
+		return nil, fmt.Errorf("unexpected ed25519 private key size %d", len(data))
+	}
+	return ed25519.PrivateKey(data), nil
+}
+
+func decodeBlock(s, header, footer string) ([]byte, error) {
+	body := strings.TrimSpace(s)
+	body = strings.TrimPrefix(body, header)
+	body = strings.TrimSuffix(body, footer)
+	body = strings.TrimSpace(body)
+
+	data, err := base64.StdEncoding.DecodeString(body)
+	if err != nil {
+		return nil, fmt.Errorf("invalid base64 body: %v", err)
+	}
+
+	return data, nil
+}
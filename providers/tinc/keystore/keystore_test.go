@@ -0,0 +1,66 @@
+package keystore
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"strings"
+	"testing"
+)
+
+func TestPublicKeyRoundTrip(t *testing.T) {
+	pub, _, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("generating ed25519 key: %v", err)
+	}
+
+	encoded := EncodePublicKey(pub)
+	if !strings.HasPrefix(encoded, publicKeyHeader) || !strings.HasSuffix(strings.TrimSpace(encoded), publicKeyFooter) {
+		t.Fatalf("encoded public key missing banner: %q", encoded)
+	}
+
+	decoded, err := DecodePublicKey(encoded)
+	if err != nil {
+		t.Fatalf("DecodePublicKey: %v", err)
+	}
+	if !decoded.Equal(pub) {
+		t.Fatalf("decoded public key %x does not match original %x", decoded, pub)
+	}
+}
+
+func TestPrivateKeyRoundTrip(t *testing.T) {
+	_, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("generating ed25519 key: %v", err)
+	}
+
+	encoded := EncodePrivateKey(priv)
+
+	decoded, err := DecodePrivateKey(encoded)
+	if err != nil {
+		t.Fatalf("DecodePrivateKey: %v", err)
+	}
+	if !decoded.Equal(priv) {
+		t.Fatalf("decoded private key does not match original")
+	}
+}
+
+func TestDecodePublicKeyRejectsWrongSize(t *testing.T) {
+	short := encodeBlock(publicKeyHeader, publicKeyFooter, []byte("too short"))
+	if _, err := DecodePublicKey(short); err == nil {
+		t.Fatal("expected an error decoding a public key of the wrong size, got nil")
+	}
+}
+
+func TestDecodePrivateKeyRejectsWrongSize(t *testing.T) {
+	short := encodeBlock(privateKeyHeader, privateKeyFooter, []byte("too short"))
+	if _, err := DecodePrivateKey(short); err == nil {
+		t.Fatal("expected an error decoding a private key of the wrong size, got nil")
+	}
+}
+
+func TestDecodePublicKeyRejectsInvalidBase64(t *testing.T) {
+	bad := publicKeyHeader + "\nnot-valid-base64!!!\n" + publicKeyFooter
+	if _, err := DecodePublicKey(bad); err == nil {
+		t.Fatal("expected an error decoding invalid base64, got nil")
+	}
+}
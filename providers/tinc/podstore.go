@@ -0,0 +1,74 @@
+package tinc
+
+import (
+	"context"
+	"fmt"
+
+	"k8s.io/api/core/v1"
+)
+
+// PodStore persists the pods a TincProvider is tracking, keyed the same way
+// as TincProvider.containers, so pod state survives a process restart
+// instead of living only in memory.
+type PodStore interface {
+	// List returns every pod currently persisted.
+	List(ctx context.Context) ([]*v1.Pod, error)
+	// Get returns a pod by key, and false if no such pod is stored.
+	Get(ctx context.Context, key string) (*v1.Pod, bool, error)
+	// Put creates or replaces the pod stored under key.
+	Put(ctx context.Context, key string, pod *v1.Pod) error
+	// Delete removes the pod stored under key, if any.
+	Delete(ctx context.Context, key string) error
+	// Close releases any resources (file handles, client connections) held
+	// by the store.
+	Close() error
+}
+
+const (
+	// podStoreBolt and podStoreEtcd select the two StoreConfig.Backend
+	// values.
+	podStoreBolt string = "bbolt"
+	podStoreEtcd string = "etcd"
+
+	// defaultPodStorePath is where the bbolt-backed store keeps its file,
+	// under a subdirectory named after the node.
+	defaultPodStorePath string = "pods.db"
+
+	// defaultEtcdPrefix namespaces this provider's keys within a shared
+	// etcd cluster.
+	defaultEtcdPrefix string = "/virtual-kubelet/tinc"
+)
+
+// StoreConfig selects and configures the PodStore backend.
+type StoreConfig struct {
+	// Backend is podStoreBolt (the default) or podStoreEtcd.
+	Backend string `json:"backend,omitempty"`
+
+	// Path is the bbolt database file, in podStoreBolt mode. Defaults to
+	// "<KeyConfig.KeyDir>/pods.db".
+	Path string `json:"path,omitempty"`
+
+	// EtcdEndpoints are the etcd cluster members to connect to, in
+	// podStoreEtcd mode.
+	EtcdEndpoints []string `json:"etcdEndpoints,omitempty"`
+
+	// EtcdPrefix namespaces this provider's keys within the etcd cluster.
+	// Also used as the election prefix multiple virtual-kubelets backing
+	// the same node name campaign under, so only one of them writes at a
+	// time.
+	EtcdPrefix string `json:"etcdPrefix,omitempty"`
+}
+
+// newPodStore builds the PodStore backend selected by cfg.
+func newPodStore(cfg StoreConfig, nodeName string) (PodStore, error) {
+	switch cfg.Backend {
+	case "", podStoreBolt:
+		return NewBoltPodStore(cfg.Path)
+
+	case podStoreEtcd:
+		return NewEtcdPodStore(cfg.EtcdEndpoints, cfg.EtcdPrefix, nodeName)
+
+	default:
+		return nil, fmt.Errorf("unknown pod store backend %q", cfg.Backend)
+	}
+}
@@ -0,0 +1,92 @@
+package tinc
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	bolt "go.etcd.io/bbolt"
+	"k8s.io/api/core/v1"
+)
+
+// podsBucket is the single bbolt bucket pods are stored in.
+var podsBucket = []byte("pods")
+
+// boltPodStore persists pods to a local bbolt database file, for
+// single-replica deployments that don't need an external store.
+type boltPodStore struct {
+	db *bolt.DB
+}
+
+// NewBoltPodStore opens (creating if necessary) a bbolt database at path.
+func NewBoltPodStore(path string) (*boltPodStore, error) {
+	db, err := bolt.Open(path, 0600, nil)
+	if err != nil {
+		return nil, fmt.Errorf("opening bbolt pod store at %s: %v", path, err)
+	}
+
+	err = db.Update(func(tx *bolt.Tx) error {
+		_, err := tx.CreateBucketIfNotExists(podsBucket)
+		return err
+	})
+	if err != nil {
+		db.Close()
+		return nil, fmt.Errorf("creating pods bucket: %v", err)
+	}
+
+	return &boltPodStore{db: db}, nil
+}
+
+func (s *boltPodStore) List(ctx context.Context) ([]*v1.Pod, error) {
+	var pods []*v1.Pod
+
+	err := s.db.View(func(tx *bolt.Tx) error {
+		return tx.Bucket(podsBucket).ForEach(func(_, data []byte) error {
+			pod := &v1.Pod{}
+			if err := json.Unmarshal(data, pod); err != nil {
+				return err
+			}
+			pods = append(pods, pod)
+			return nil
+		})
+	})
+
+	return pods, err
+}
+
+func (s *boltPodStore) Get(ctx context.Context, key string) (*v1.Pod, bool, error) {
+	var pod *v1.Pod
+
+	err := s.db.View(func(tx *bolt.Tx) error {
+		data := tx.Bucket(podsBucket).Get([]byte(key))
+		if data == nil {
+			return nil
+		}
+
+		pod = &v1.Pod{}
+		return json.Unmarshal(data, pod)
+	})
+
+	return pod, pod != nil, err
+}
+
+func (s *boltPodStore) Put(ctx context.Context, key string, pod *v1.Pod) error {
+	data, err := json.Marshal(pod)
+	if err != nil {
+		return err
+	}
+
+	return s.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket(podsBucket).Put([]byte(key), data)
+	})
+}
+
+func (s *boltPodStore) Delete(ctx context.Context, key string) error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket(podsBucket).Delete([]byte(key))
+	})
+}
+
+func (s *boltPodStore) Close() error {
+	return s.db.Close()
+}
@@ -0,0 +1,173 @@
+package tinc
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"time"
+
+	"go.etcd.io/etcd/clientv3"
+	"go.etcd.io/etcd/clientv3/concurrency"
+	"k8s.io/api/core/v1"
+)
+
+// etcdPodStore persists pods in etcd, for HA deployments where several
+// virtual-kubelets back the same node name. Only the one that wins the
+// lease-based election for nodeName actually writes; the rest can still
+// List/Get so GetPods/GetPod keep working on a standby replica.
+type etcdPodStore struct {
+	client   *clientv3.Client
+	session  *concurrency.Session
+	election *concurrency.Election
+	prefix   string
+	nodeName string
+}
+
+// NewEtcdPodStore connects to the given etcd endpoints and starts campaigning
+// for leadership of nodeName under prefix in the background. It returns as
+// soon as the connection is up, without waiting to win the election, so a
+// standby replica can still List/Get pods (see isLeader) while it campaigns;
+// only Put/Delete require having actually won.
+func NewEtcdPodStore(endpoints []string, prefix, nodeName string) (*etcdPodStore, error) {
+	if prefix == "" {
+		prefix = defaultEtcdPrefix
+	}
+
+	client, err := clientv3.New(clientv3.Config{Endpoints: endpoints, DialTimeout: 5 * time.Second})
+	if err != nil {
+		return nil, fmt.Errorf("connecting to etcd: %v", err)
+	}
+
+	session, err := concurrency.NewSession(client)
+	if err != nil {
+		client.Close()
+		return nil, fmt.Errorf("creating etcd session: %v", err)
+	}
+
+	election := concurrency.NewElection(session, prefix+"/leader/"+nodeName)
+
+	go func() {
+		if err := election.Campaign(context.Background(), nodeName); err != nil {
+			log.Printf("campaigning for leadership of node %q: %v", nodeName, err)
+		}
+	}()
+
+	return &etcdPodStore{client: client, session: session, election: election, prefix: prefix + "/pods/", nodeName: nodeName}, nil
+}
+
+func (s *etcdPodStore) List(ctx context.Context) ([]*v1.Pod, error) {
+	resp, err := s.client.Get(ctx, s.prefix, clientv3.WithPrefix())
+	if err != nil {
+		return nil, fmt.Errorf("listing pods from etcd: %v", err)
+	}
+
+	pods := make([]*v1.Pod, 0, len(resp.Kvs))
+	for _, kv := range resp.Kvs {
+		pod := &v1.Pod{}
+		if err := json.Unmarshal(kv.Value, pod); err != nil {
+			return nil, fmt.Errorf("decoding pod %q from etcd: %v", kv.Key, err)
+		}
+		pods = append(pods, pod)
+	}
+
+	return pods, nil
+}
+
+func (s *etcdPodStore) Get(ctx context.Context, key string) (*v1.Pod, bool, error) {
+	resp, err := s.client.Get(ctx, s.prefix+key)
+	if err != nil {
+		return nil, false, fmt.Errorf("getting pod %q from etcd: %v", key, err)
+	}
+	if len(resp.Kvs) == 0 {
+		return nil, false, nil
+	}
+
+	pod := &v1.Pod{}
+	if err := json.Unmarshal(resp.Kvs[0].Value, pod); err != nil {
+		return nil, false, fmt.Errorf("decoding pod %q from etcd: %v", key, err)
+	}
+
+	return pod, true, nil
+}
+
+func (s *etcdPodStore) Put(ctx context.Context, key string, pod *v1.Pod) error {
+	if err := s.awaitLeadership(ctx); err != nil {
+		return fmt.Errorf("refusing to store pod %q: %v", key, err)
+	}
+
+	data, err := json.Marshal(pod)
+	if err != nil {
+		return err
+	}
+
+	// Pod keys are not tied to the election session's lease: that lease
+	// dies with this process, and tying pod state to it would mean a
+	// restart TTLs every pod out of etcd instead of the state surviving
+	// the restart, which is the whole point of this store.
+	_, err = s.client.Put(ctx, s.prefix+key, string(data))
+	return err
+}
+
+func (s *etcdPodStore) Delete(ctx context.Context, key string) error {
+	if err := s.awaitLeadership(ctx); err != nil {
+		return fmt.Errorf("refusing to delete pod %q: %v", key, err)
+	}
+
+	_, err := s.client.Delete(ctx, s.prefix+key)
+	return err
+}
+
+// isLeader reports whether this process currently holds the election for
+// its node name.
+func (s *etcdPodStore) isLeader(ctx context.Context) bool {
+	resp, err := s.election.Leader(ctx)
+	if err != nil || len(resp.Kvs) == 0 {
+		return false
+	}
+	// Every replica campaigns with the same value (nodeName), so the leader
+	// key's lease, unique to this process's session, is what identifies us.
+	return resp.Kvs[0].Lease == int64(s.session.Lease())
+}
+
+// leaderWaitTimeout bounds how long Put/Delete wait for this replica to win
+// the election before giving up. NewEtcdPodStore campaigns in the
+// background and returns immediately so a standby can still List/Get right
+// away; without this wait, the first write on the replica that's actually
+// meant to be primary would spuriously fail with "not the leader" for
+// however long the campaign takes to resolve.
+const leaderWaitTimeout = 5 * time.Second
+
+// awaitLeadership blocks until this process holds the election for its node
+// name, ctx is done, or leaderWaitTimeout elapses, whichever comes first.
+func (s *etcdPodStore) awaitLeadership(ctx context.Context) error {
+	if s.isLeader(ctx) {
+		return nil
+	}
+
+	waitCtx, cancel := context.WithTimeout(ctx, leaderWaitTimeout)
+	defer cancel()
+
+	ch := s.election.Observe(waitCtx)
+	for {
+		select {
+		case resp, ok := <-ch:
+			if !ok {
+				return fmt.Errorf("not the leader for node %q", s.nodeName)
+			}
+			if len(resp.Kvs) > 0 && resp.Kvs[0].Lease == int64(s.session.Lease()) {
+				return nil
+			}
+		case <-waitCtx.Done():
+			return fmt.Errorf("not the leader for node %q after waiting %s for the election", s.nodeName, leaderWaitTimeout)
+		}
+	}
+}
+
+func (s *etcdPodStore) Close() error {
+	if err := s.session.Close(); err != nil {
+		s.client.Close()
+		return err
+	}
+	return s.client.Close()
+}
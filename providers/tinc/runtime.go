@@ -0,0 +1,157 @@
+package tinc
+
+import (
+	"context"
+	"fmt"
+	"io"
+)
+
+// Runtime abstracts the container engine a TincProvider drives. It exists so
+// CreatePod/DeletePod don't shell out to a hard-coded docker binary, and so
+// GetContainerLogs/ExecInContainer/GetStatsSummary have something to call
+// against regardless of which engine actually backs the node.
+type Runtime interface {
+	// Run starts a new container from spec and returns its ID.
+	Run(ctx context.Context, spec RunSpec) (ContainerID, error)
+	// Remove force-removes a container by ID. It must not return an error
+	// if the container is already gone.
+	Remove(ctx context.Context, id ContainerID) error
+	// Inspect returns the current state of a container.
+	Inspect(ctx context.Context, id ContainerID) (ContainerInfo, error)
+	// List returns the containers carrying the given label key=value, or
+	// carrying labelKey with any value when labelValue is empty.
+	List(ctx context.Context, labelKey, labelValue string) ([]ContainerInfo, error)
+	// Logs streams a container's logs according to opts.
+	Logs(ctx context.Context, id ContainerID, opts LogOptions) (io.ReadCloser, error)
+	// Stats returns a point-in-time resource usage snapshot for a container.
+	Stats(ctx context.Context, id ContainerID) (Stats, error)
+	// Exec runs a command inside a running container.
+	Exec(ctx context.Context, id ContainerID, spec ExecSpec) error
+}
+
+// ContainerID is the engine-native identifier of a container (docker ID,
+// podman ID, containerd container+task ID, ...).
+type ContainerID string
+
+// PortMapping publishes a container port on the host.
+type PortMapping struct {
+	HostPort      int32
+	ContainerPort int32
+	Protocol      string
+}
+
+// VolumeMount binds a host path into the container.
+type VolumeMount struct {
+	HostPath      string
+	ContainerPath string
+	ReadOnly      bool
+}
+
+// ResourceLimits mirrors the subset of v1.ResourceRequirements the runtimes
+// know how to enforce.
+type ResourceLimits struct {
+	CPUs        string // fractional CPUs, e.g. "0.5"
+	MemoryBytes int64
+}
+
+// SecurityOptions mirrors the subset of v1.SecurityContext the runtimes
+// know how to enforce. The containerd backend applies Privileged, User and
+// ReadOnlyRootFS only; it does not apply CapAdd or CapDrop.
+type SecurityOptions struct {
+	Privileged      bool
+	User            string
+	ReadOnlyRootFS  bool
+	CapAdd, CapDrop []string
+}
+
+// RunSpec describes a container to start. A RunSpec with NetworkMode set to
+// "container:<id>" joins the network namespace of an already-running
+// container, which is how pod containers join the tinc node they belong to.
+type RunSpec struct {
+	Name        string
+	Image       string
+	Command     []string
+	Args        []string
+	Env         map[string]string
+	WorkingDir  string
+	Labels      map[string]string
+	NetworkMode string
+	Ports       []PortMapping
+	Volumes     []VolumeMount
+	Resources   ResourceLimits
+	Security    SecurityOptions
+	Detach      bool
+}
+
+// ContainerInfo is the normalized state the provider needs out of an
+// inspect/list call, independent of which engine produced it.
+type ContainerInfo struct {
+	ID           ContainerID
+	Name         string
+	Labels       map[string]string
+	Running      bool
+	ExitCode     int
+	Status       string
+	RestartCount int32
+}
+
+// LogOptions controls GetContainerLogs. It matches the signature already in
+// use by the other in-tree providers.
+type LogOptions struct {
+	Tail         int
+	Follow       bool
+	SinceSeconds int
+	Timestamps   bool
+	Previous     bool
+}
+
+// ExecSpec describes a one-off command to run inside a container.
+type ExecSpec struct {
+	Command []string
+	Stdin   io.Reader
+	Stdout  io.WriteCloser
+	Stderr  io.WriteCloser
+	TTY     bool
+	Resize  <-chan TerminalSize
+}
+
+// TerminalSize mirrors remotecommand.TerminalSize so this package doesn't
+// have to import client-go just to describe a resize event.
+type TerminalSize struct {
+	Width, Height uint16
+}
+
+// Stats is a point-in-time resource usage snapshot for a single container.
+type Stats struct {
+	CPUUsageNanoCores     uint64
+	MemoryWorkingSetBytes uint64
+	MemoryUsageBytes      uint64
+	NetworkRxBytes        uint64
+	NetworkTxBytes        uint64
+}
+
+const (
+	// RuntimeDocker selects the docker engine client backend.
+	RuntimeDocker = "docker"
+	// RuntimePodman selects the podman REST bindings backend.
+	RuntimePodman = "podman"
+	// RuntimeContainerd selects the containerd client backend.
+	RuntimeContainerd = "containerd"
+
+	// DefaultRuntime is used when TincConfig.Runtime is left empty.
+	DefaultRuntime = RuntimeDocker
+)
+
+// newRuntime builds the Runtime backend selected by name.
+func newRuntime(name string) (Runtime, error) {
+	switch name {
+	case "", RuntimeDocker:
+		return newDockerRuntime()
+	case RuntimePodman:
+		return newPodmanRuntime()
+	case RuntimeContainerd:
+		return newContainerdRuntime()
+	default:
+		return nil, fmt.Errorf("unknown tinc runtime %q", name)
+	}
+}
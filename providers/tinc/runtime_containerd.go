@@ -0,0 +1,241 @@
+package tinc
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/containerd/containerd"
+	"github.com/containerd/containerd/cio"
+	"github.com/containerd/containerd/namespaces"
+	"github.com/containerd/containerd/oci"
+	specs "github.com/opencontainers/runtime-spec/specs-go"
+)
+
+const containerdNamespace = "virtual-kubelet"
+
+// containerdRuntime implements Runtime directly against containerd, for
+// nodes that run a bare containerd rather than docker or podman.
+type containerdRuntime struct {
+	client *containerd.Client
+}
+
+func newContainerdRuntime() (Runtime, error) {
+	client, err := containerd.New("/run/containerd/containerd.sock")
+	if err != nil {
+		return nil, fmt.Errorf("connecting to containerd: %v", err)
+	}
+	return &containerdRuntime{client: client}, nil
+}
+
+func (r *containerdRuntime) ctx(ctx context.Context) context.Context {
+	return namespaces.WithNamespace(ctx, containerdNamespace)
+}
+
+// networkNamespacePath returns the /proc/<pid>/ns/net path of the running
+// task backing containerID, so a new container can join its network
+// namespace the way RunSpec.NetworkMode "container:<id>" asks for. ctx must
+// already carry the containerd namespace.
+func (r *containerdRuntime) networkNamespacePath(ctx context.Context, containerID string) (string, error) {
+	peer, err := r.client.LoadContainer(ctx, containerID)
+	if err != nil {
+		return "", fmt.Errorf("loading container %q: %v", containerID, err)
+	}
+
+	task, err := peer.Task(ctx, nil)
+	if err != nil {
+		return "", fmt.Errorf("getting task for container %q: %v", containerID, err)
+	}
+
+	return fmt.Sprintf("/proc/%d/ns/net", task.Pid()), nil
+}
+
+func (r *containerdRuntime) Run(ctx context.Context, spec RunSpec) (ContainerID, error) {
+	ctx = r.ctx(ctx)
+
+	image, err := r.client.Pull(ctx, spec.Image, containerd.WithPullUnpack)
+	if err != nil {
+		return "", fmt.Errorf("pulling image %q: %v", spec.Image, err)
+	}
+
+	opts := []oci.SpecOpts{oci.WithImageConfig(image)}
+	if len(spec.Command) > 0 {
+		opts = append(opts, oci.WithProcessArgs(append(spec.Command, spec.Args...)...))
+	}
+	if spec.WorkingDir != "" {
+		opts = append(opts, oci.WithProcessCwd(spec.WorkingDir))
+	}
+	var env []string
+	for k, v := range spec.Env {
+		env = append(env, k+"="+v)
+	}
+	opts = append(opts, oci.WithEnv(env))
+	if spec.Security.Privileged {
+		opts = append(opts, oci.WithPrivileged)
+	}
+	if spec.Security.User != "" {
+		opts = append(opts, oci.WithUser(spec.Security.User))
+	}
+	if spec.Security.ReadOnlyRootFS {
+		opts = append(opts, oci.WithRootFSReadonly())
+	}
+	for _, v := range spec.Volumes {
+		mountOpts := []string{"rbind"}
+		if v.ReadOnly {
+			mountOpts = append(mountOpts, "ro")
+		} else {
+			mountOpts = append(mountOpts, "rw")
+		}
+		opts = append(opts, oci.WithMounts([]specs.Mount{{
+			Destination: v.ContainerPath,
+			Type:        "bind",
+			Source:      v.HostPath,
+			Options:     mountOpts,
+		}}))
+	}
+	if spec.Resources.MemoryBytes > 0 {
+		opts = append(opts, oci.WithMemoryLimit(uint64(spec.Resources.MemoryBytes)))
+	}
+	if spec.Resources.CPUs != "" {
+		// CFS quota/period are in microseconds; a 100ms period with a
+		// quota of cpus*100000us caps usage at cpus cores, same as
+		// dockerRuntime's NanoCPUs conversion.
+		const cfsPeriodUs = 100000
+		quota := cpusToNanoCPUs(spec.Resources.CPUs) * cfsPeriodUs / 1e9
+		opts = append(opts, oci.WithCPUCFS(quota, cfsPeriodUs))
+	}
+	if strings.HasPrefix(spec.NetworkMode, "container:") {
+		peerID := strings.TrimPrefix(spec.NetworkMode, "container:")
+		nsPath, err := r.networkNamespacePath(ctx, peerID)
+		if err != nil {
+			return "", fmt.Errorf("joining network namespace of %q: %v", peerID, err)
+		}
+		opts = append(opts, oci.WithLinuxNamespace(specs.LinuxNamespace{Type: specs.NetworkNamespace, Path: nsPath}))
+	}
+
+	container, err := r.client.NewContainer(ctx, spec.Name,
+		containerd.WithImage(image),
+		containerd.WithNewSnapshot(spec.Name+"-snapshot", image),
+		containerd.WithNewSpec(opts...),
+		containerd.WithContainerLabels(spec.Labels),
+	)
+	if err != nil {
+		return "", fmt.Errorf("creating containerd container %q: %v", spec.Name, err)
+	}
+
+	task, err := container.NewTask(ctx, cio.NewCreator(cio.WithStdio))
+	if err != nil {
+		return "", fmt.Errorf("creating task for container %q: %v", spec.Name, err)
+	}
+
+	if err := task.Start(ctx); err != nil {
+		return "", fmt.Errorf("starting task for container %q: %v", spec.Name, err)
+	}
+
+	return ContainerID(spec.Name), nil
+}
+
+func (r *containerdRuntime) Remove(ctx context.Context, id ContainerID) error {
+	ctx = r.ctx(ctx)
+
+	container, err := r.client.LoadContainer(ctx, string(id))
+	if err != nil {
+		return nil
+	}
+
+	if task, err := container.Task(ctx, nil); err == nil {
+		_, _ = task.Delete(ctx, containerd.WithProcessKill)
+	}
+
+	return container.Delete(ctx, containerd.WithSnapshotCleanup)
+}
+
+func (r *containerdRuntime) Inspect(ctx context.Context, id ContainerID) (ContainerInfo, error) {
+	ctx = r.ctx(ctx)
+
+	container, err := r.client.LoadContainer(ctx, string(id))
+	if err != nil {
+		return ContainerInfo{}, fmt.Errorf("loading container %s: %v", id, err)
+	}
+
+	info := ContainerInfo{ID: id, Name: string(id)}
+
+	labels, err := container.Labels(ctx)
+	if err == nil {
+		info.Labels = labels
+	}
+
+	task, err := container.Task(ctx, nil)
+	if err != nil {
+		info.Status = "created"
+		return info, nil
+	}
+
+	status, err := task.Status(ctx)
+	if err != nil {
+		return info, fmt.Errorf("getting task status for container %s: %v", id, err)
+	}
+
+	info.Status = string(status.Status)
+	info.Running = status.Status == containerd.Running
+	info.ExitCode = int(status.ExitStatus)
+
+	return info, nil
+}
+
+func (r *containerdRuntime) List(ctx context.Context, labelKey, labelValue string) ([]ContainerInfo, error) {
+	ctx = r.ctx(ctx)
+
+	// An empty labelValue means "has this label, any value" (how
+	// reconcileContainers/reconcilePeerContainers call List); containerd's
+	// filter syntax needs the bare key for that, since "labels.key==""
+	// only matches a container whose label is explicitly set to "".
+	filter := fmt.Sprintf("labels.%q", labelKey)
+	if labelValue != "" {
+		filter = fmt.Sprintf("labels.%q==%q", labelKey, labelValue)
+	}
+
+	containerList, err := r.client.Containers(ctx, filter)
+	if err != nil {
+		return nil, fmt.Errorf("listing containerd containers: %v", err)
+	}
+
+	infos := make([]ContainerInfo, 0, len(containerList))
+	for _, c := range containerList {
+		info, err := r.Inspect(ctx, ContainerID(c.ID()))
+		if err != nil {
+			continue
+		}
+		infos = append(infos, info)
+	}
+
+	return infos, nil
+}
+
+// Logs is unsupported on the containerd backend: Run attaches a container's
+// stdio directly at task-creation time (cio.WithStdio) rather than through a
+// daemon that buffers and replays it, so there is nothing for a later Logs
+// call to read from. GetContainerLogs on a containerd-backed node fails
+// with this error; use the docker runtime backend for log streaming until
+// containerd support is built out (e.g. by attaching stdio to log files at
+// Run time instead).
+func (r *containerdRuntime) Logs(ctx context.Context, id ContainerID, opts LogOptions) (io.ReadCloser, error) {
+	return nil, fmt.Errorf("containerd runtime: log streaming is not supported yet, use the docker runtime backend")
+}
+
+// Stats is unsupported on the containerd backend pending a cgroups metrics
+// reader for the task's cgroup. GetStatsSummary skips containers tracked
+// against a containerd-backed node; use the docker runtime backend for
+// stats until containerd support is built out.
+func (r *containerdRuntime) Stats(ctx context.Context, id ContainerID) (Stats, error) {
+	return Stats{}, fmt.Errorf("containerd runtime: stats is not supported yet, use the docker runtime backend")
+}
+
+// Exec is unsupported on the containerd backend pending a task.Exec-based
+// implementation. ExecInContainer on a containerd-backed node fails with
+// this error; use the docker runtime backend for exec until containerd
+// support is built out.
+func (r *containerdRuntime) Exec(ctx context.Context, id ContainerID, spec ExecSpec) error {
+	return fmt.Errorf("containerd runtime: exec is not supported yet, use the docker runtime backend")
+}
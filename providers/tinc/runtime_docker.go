@@ -0,0 +1,353 @@
+package tinc
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"time"
+
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/api/types/filters"
+	dockerclient "github.com/docker/docker/client"
+	"github.com/docker/docker/pkg/stdcopy"
+	"github.com/docker/go-connections/nat"
+)
+
+// dockerRuntime implements Runtime against a local docker daemon using the
+// official docker client instead of shelling out to the docker CLI.
+type dockerRuntime struct {
+	client *dockerclient.Client
+}
+
+func newDockerRuntime() (Runtime, error) {
+	cli, err := dockerclient.NewClientWithOpts(dockerclient.FromEnv, dockerclient.WithAPIVersionNegotiation())
+	if err != nil {
+		return nil, fmt.Errorf("creating docker client: %v", err)
+	}
+	return &dockerRuntime{client: cli}, nil
+}
+
+func (r *dockerRuntime) Run(ctx context.Context, spec RunSpec) (ContainerID, error) {
+	cfg := &container.Config{
+		Image:      spec.Image,
+		Entrypoint: spec.Command,
+		Cmd:        spec.Args,
+		WorkingDir: spec.WorkingDir,
+		Labels:     spec.Labels,
+		User:       spec.Security.User,
+	}
+	for k, v := range spec.Env {
+		cfg.Env = append(cfg.Env, k+"="+v)
+	}
+
+	hostCfg := &container.HostConfig{
+		AutoRemove:     false,
+		Privileged:     spec.Security.Privileged,
+		CapAdd:         spec.Security.CapAdd,
+		CapDrop:        spec.Security.CapDrop,
+		ReadonlyRootfs: spec.Security.ReadOnlyRootFS,
+	}
+	if spec.NetworkMode != "" {
+		hostCfg.NetworkMode = container.NetworkMode(spec.NetworkMode)
+	}
+	if spec.Resources.MemoryBytes > 0 {
+		hostCfg.Resources.Memory = spec.Resources.MemoryBytes
+	}
+	if spec.Resources.CPUs != "" {
+		hostCfg.Resources.NanoCPUs = cpusToNanoCPUs(spec.Resources.CPUs)
+	}
+	for _, v := range spec.Volumes {
+		bind := v.HostPath + ":" + v.ContainerPath
+		if v.ReadOnly {
+			bind += ":ro"
+		}
+		hostCfg.Binds = append(hostCfg.Binds, bind)
+	}
+
+	// Port publishing only applies to a container that owns its own network
+	// namespace (the tinc node); containers joining it via NetworkMode
+	// "container:<id>" inherit whatever the tinc node already published.
+	if spec.NetworkMode == "" && len(spec.Ports) > 0 {
+		cfg.ExposedPorts = nat.PortSet{}
+		hostCfg.PortBindings = nat.PortMap{}
+		for _, p := range spec.Ports {
+			proto := p.Protocol
+			if proto == "" {
+				proto = "tcp"
+			}
+			containerPort := nat.Port(fmt.Sprintf("%d/%s", p.ContainerPort, proto))
+			cfg.ExposedPorts[containerPort] = struct{}{}
+			hostCfg.PortBindings[containerPort] = []nat.PortBinding{{HostPort: fmt.Sprintf("%d", p.HostPort)}}
+		}
+	}
+
+	resp, err := r.client.ContainerCreate(ctx, cfg, hostCfg, nil, nil, spec.Name)
+	if err != nil {
+		return "", fmt.Errorf("creating container %q: %v", spec.Name, err)
+	}
+
+	if err := r.client.ContainerStart(ctx, resp.ID, types.ContainerStartOptions{}); err != nil {
+		return "", fmt.Errorf("starting container %q: %v", spec.Name, err)
+	}
+
+	return ContainerID(resp.ID), nil
+}
+
+func (r *dockerRuntime) Remove(ctx context.Context, id ContainerID) error {
+	err := r.client.ContainerRemove(ctx, string(id), types.ContainerRemoveOptions{Force: true})
+	if dockerclient.IsErrNotFound(err) {
+		return nil
+	}
+	return err
+}
+
+func (r *dockerRuntime) Inspect(ctx context.Context, id ContainerID) (ContainerInfo, error) {
+	resp, err := r.client.ContainerInspect(ctx, string(id))
+	if err != nil {
+		return ContainerInfo{}, fmt.Errorf("inspecting container %s: %v", id, err)
+	}
+
+	info := ContainerInfo{
+		ID:           ContainerID(resp.ID),
+		Name:         resp.Name,
+		Labels:       resp.Config.Labels,
+		RestartCount: int32(resp.RestartCount),
+	}
+	if resp.State != nil {
+		info.Running = resp.State.Running
+		info.ExitCode = resp.State.ExitCode
+		info.Status = resp.State.Status
+	}
+
+	return info, nil
+}
+
+func (r *dockerRuntime) List(ctx context.Context, labelKey, labelValue string) ([]ContainerInfo, error) {
+	f := filters.NewArgs()
+	if labelValue != "" {
+		f.Add("label", labelKey+"="+labelValue)
+	} else {
+		f.Add("label", labelKey)
+	}
+
+	containers, err := r.client.ContainerList(ctx, types.ContainerListOptions{All: true, Filters: f})
+	if err != nil {
+		return nil, fmt.Errorf("listing containers: %v", err)
+	}
+
+	infos := make([]ContainerInfo, 0, len(containers))
+	for _, c := range containers {
+		name := ""
+		if len(c.Names) > 0 {
+			name = c.Names[0]
+		}
+		infos = append(infos, ContainerInfo{
+			ID:      ContainerID(c.ID),
+			Name:    name,
+			Labels:  c.Labels,
+			Running: c.State == "running",
+			Status:  c.State,
+		})
+	}
+
+	return infos, nil
+}
+
+// Logs streams a container's logs. opts.Previous is ignored: docker has no
+// notion of "logs from the previous instance of this container" the way a
+// kubelet expects for a restarted container, since runPodContainer always
+// removes and recreates the named container rather than restarting it in
+// place, so there is never a distinct previous instance to read from.
+func (r *dockerRuntime) Logs(ctx context.Context, id ContainerID, opts LogOptions) (io.ReadCloser, error) {
+	options := types.ContainerLogsOptions{
+		ShowStdout: true,
+		ShowStderr: true,
+		Follow:     opts.Follow,
+		Timestamps: opts.Timestamps,
+	}
+	if opts.Tail > 0 {
+		options.Tail = fmt.Sprintf("%d", opts.Tail)
+	}
+	if opts.SinceSeconds > 0 {
+		options.Since = fmt.Sprintf("%ds", opts.SinceSeconds)
+	}
+
+	return r.client.ContainerLogs(ctx, string(id), options)
+}
+
+// Stats takes two samples a short interval apart to compute a real CPU
+// usage rate: ContainerStatsOneShot leaves PreCPUStats zeroed, so a single
+// sample's cpuDelta/sysDelta is the container's cumulative usage since start
+// divided by the host's cumulative usage since boot, not a rate, and drifts
+// further from reality the longer the container has been running.
+func (r *dockerRuntime) Stats(ctx context.Context, id ContainerID) (Stats, error) {
+	first, err := r.statsSnapshot(ctx, id)
+	if err != nil {
+		return Stats{}, err
+	}
+
+	select {
+	case <-ctx.Done():
+		return Stats{}, ctx.Err()
+	case <-time.After(statsSampleInterval):
+	}
+
+	second, err := r.statsSnapshot(ctx, id)
+	if err != nil {
+		return Stats{}, err
+	}
+
+	cpuDelta := float64(second.CPUStats.CPUUsage.TotalUsage - first.CPUStats.CPUUsage.TotalUsage)
+	sysDelta := float64(second.CPUStats.SystemUsage - first.CPUStats.SystemUsage)
+	var cpuNanoCores uint64
+	if sysDelta > 0 {
+		cpuNanoCores = uint64(cpuDelta / sysDelta * float64(len(second.CPUStats.CPUUsage.PercpuUsage)) * 1e9)
+	}
+
+	var rx, tx uint64
+	for _, n := range second.Networks {
+		rx += n.RxBytes
+		tx += n.TxBytes
+	}
+
+	return Stats{
+		CPUUsageNanoCores:     cpuNanoCores,
+		MemoryUsageBytes:      second.MemoryStats.Usage,
+		MemoryWorkingSetBytes: memoryWorkingSetBytes(second.MemoryStats),
+		NetworkRxBytes:        rx,
+		NetworkTxBytes:        tx,
+	}, nil
+}
+
+// statsSampleInterval is how far apart the two samples Stats takes are, to
+// turn docker's cumulative CPU counters into a rate.
+const statsSampleInterval = 200 * time.Millisecond
+
+func (r *dockerRuntime) statsSnapshot(ctx context.Context, id ContainerID) (types.StatsJSON, error) {
+	resp, err := r.client.ContainerStatsOneShot(ctx, string(id))
+	if err != nil {
+		return types.StatsJSON{}, fmt.Errorf("getting stats for container %s: %v", id, err)
+	}
+	defer resp.Body.Close()
+
+	var raw types.StatsJSON
+	if err := decodeJSON(resp.Body, &raw); err != nil {
+		return types.StatsJSON{}, fmt.Errorf("decoding stats for container %s: %v", id, err)
+	}
+	return raw, nil
+}
+
+// memoryWorkingSetBytes subtracts the page cache from total usage the way
+// kubelet defines working set. The cgroup v1 stats key is "cache"; cgroup v2
+// reports the same figure under "file" and has no "cache" key at all, so
+// checking only "cache" silently overcounts working set to full usage on a
+// cgroup v2 host.
+func memoryWorkingSetBytes(mem types.MemoryStats) uint64 {
+	cache, ok := mem.Stats["cache"]
+	if !ok {
+		cache = mem.Stats["file"]
+	}
+	if cache > mem.Usage {
+		return 0
+	}
+	return mem.Usage - cache
+}
+
+func (r *dockerRuntime) Exec(ctx context.Context, id ContainerID, spec ExecSpec) error {
+	execResp, err := r.client.ContainerExecCreate(ctx, string(id), types.ExecConfig{
+		Cmd:          spec.Command,
+		AttachStdin:  spec.Stdin != nil,
+		AttachStdout: spec.Stdout != nil,
+		AttachStderr: spec.Stderr != nil,
+		Tty:          spec.TTY,
+	})
+	if err != nil {
+		return fmt.Errorf("creating exec for container %s: %v", id, err)
+	}
+
+	hijacked, err := r.client.ContainerExecAttach(ctx, execResp.ID, types.ExecStartCheck{Tty: spec.TTY})
+	if err != nil {
+		return fmt.Errorf("attaching exec for container %s: %v", id, err)
+	}
+	defer hijacked.Close()
+
+	go forwardResize(ctx, r.client, execResp.ID, spec.Resize)
+
+	return streamExecIO(hijacked, spec)
+}
+
+// cpusToNanoCPUs converts a fractional CPU count (docker CLI's --cpus) into
+// the NanoCPUs value the engine API expects.
+func cpusToNanoCPUs(cpus string) int64 {
+	f, err := strconv.ParseFloat(cpus, 64)
+	if err != nil {
+		return 0
+	}
+	return int64(f * 1e9)
+}
+
+// decodeJSON is a small indirection so Stats can decode the engine's raw
+// stats payload without pulling in encoding/json twice across this file.
+func decodeJSON(r io.Reader, v interface{}) error {
+	return json.NewDecoder(r).Decode(v)
+}
+
+// forwardResize relays TTY resize events to the running exec instance until
+// ctx is done or the channel is closed.
+func forwardResize(ctx context.Context, cli *dockerclient.Client, execID string, resize <-chan TerminalSize) {
+	if resize == nil {
+		return
+	}
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case size, ok := <-resize:
+			if !ok {
+				return
+			}
+			_ = cli.ContainerExecResize(ctx, execID, types.ResizeOptions{
+				Height: uint(size.Height),
+				Width:  uint(size.Width),
+			})
+		}
+	}
+}
+
+// streamExecIO copies stdin into the hijacked exec connection and streams
+// its output back out. A TTY exec has a single raw stream that maps
+// directly onto spec.Stdout; a non-TTY exec multiplexes stdout and stderr
+// behind stdcopy frame headers and must be demultiplexed, or stdout ends up
+// with frame headers interleaved into it and stderr is silently dropped.
+func streamExecIO(hijacked types.HijackedResponse, spec ExecSpec) error {
+	errCh := make(chan error, 1)
+
+	go func() {
+		if spec.Stdin != nil {
+			_, _ = io.Copy(hijacked.Conn, spec.Stdin)
+		}
+	}()
+
+	go func() {
+		var out, errOut io.Writer = io.Discard, io.Discard
+		if spec.Stdout != nil {
+			out = spec.Stdout
+		}
+		if spec.Stderr != nil {
+			errOut = spec.Stderr
+		}
+
+		var err error
+		if spec.TTY {
+			_, err = io.Copy(out, hijacked.Reader)
+		} else {
+			_, err = stdcopy.StdCopy(out, errOut, hijacked.Reader)
+		}
+		errCh <- err
+	}()
+
+	return <-errCh
+}
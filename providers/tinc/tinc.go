@@ -2,15 +2,16 @@ package tinc
 
 import (
 	"context"
+	"crypto/ed25519"
 	"encoding/json"
 	"fmt"
 	"io"
 	"io/ioutil"
 	"log"
 	"os"
-	"os/exec"
 	"path/filepath"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/cpuguy83/strongerrors"
@@ -18,10 +19,13 @@ import (
 	"k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
 	"k8s.io/client-go/tools/remotecommand"
 	stats "k8s.io/kubernetes/pkg/kubelet/apis/stats/v1alpha1"
 
 	"github.com/virtual-kubelet/virtual-kubelet/providers"
+	"github.com/virtual-kubelet/virtual-kubelet/providers/tinc/keystore"
 )
 
 const (
@@ -37,8 +41,6 @@ const (
 
 	tincImageName string = "quay.io/dongsupark/tinc"
 
-	dockerClient = "/usr/bin/docker"
-
 	// Provider configuration defaults.
 	defaultCPUCapacity    = "20"
 	defaultMemoryCapacity = "100Gi"
@@ -65,50 +67,136 @@ const (
 
 	// DefaultTincPort is the default port number Tinc VPN listens on
 	DefaultTincPort int32 = 655
-)
 
-var (
-	myAddress      string = DefaultTincMainAddress
-	peerAddress    string = DefaultTincPeerAddress
-	privateAddress string = DefaultTincMainPrivateAddress
+	// podLabel is set on every container the runtime creates on behalf of a pod so
+	// that GetPods/GetPodStatus can rediscover them across restarts.
+	podLabel string = "vk.pod"
+
+	// podPeerLabel records which tinc peer a pod's containers joined, so
+	// reconcileContainers can restore that association across a restart.
+	podPeerLabel string = "vk.pod.peer"
 
-	tincMainName string = DefaultTincMainName
-	tincPeerName string = DefaultTincRemotePeers
+	// peerLabel is set on a peer's own tinc container, so it can be told
+	// apart from pod workload containers when rediscovering state.
+	peerLabel string = "vk.peer"
+
+	// tincPeerAnnotation lets a pod select which already-configured tinc
+	// peer its containers should join. It only ever picks among p.peers;
+	// it never adds, removes, or otherwise mutates the mesh.
+	tincPeerAnnotation string = "tinc.virtual-kubelet.io/peer"
 
-	tincStartupConfigHost      string = ""
 	tincStartupConfigContainer string = "/environment/default.startup.conf"
+	tincMainConfigContainer    string = "/service/tinc/data/tinc.conf"
+	tincUpScriptContainer      string = "/service/tinc/data/tinc-up"
+
+	// keyDistributionSecret and keyDistributionFile select the two
+	// KeyConfig.Distribution modes.
+	keyDistributionSecret string = "secret"
+	keyDistributionFile   string = "file"
 
-	tincMainConfigHost      string = ""
-	tincMainConfigContainer string = "/service/tinc/data/tinc.conf"
+	// defaultKeyBaseDir is where each node's own keypair is persisted,
+	// under a subdirectory named after the node.
+	defaultKeyBaseDir string = "/var/lib/virtual-kubelet/tinc"
 
-	tincUpScriptHost      string = ""
-	tincUpScriptContainer string = "/service/tinc/data/tinc-up"
+	// defaultKeySecretNamespace is where peer public key Secrets are read
+	// from and published to in keyDistributionSecret mode.
+	defaultKeySecretNamespace string = "kube-system"
 )
 
 // TincProvider implements the virtual-kubelet provider interface and stores pods in memory.
 type TincProvider struct {
-	nodeName    string
-	pods        map[string]*v1.Pod
-	tincAddress string
-	tincSubnet  string
-	tincPort    int32
-	config      TincConfig
+	nodeName     string
+	podsMu       sync.RWMutex
+	store        PodStore
+	containersMu sync.RWMutex
+	containers   map[string]*podContainers
+	runtime      Runtime
+	tincAddress  string
+	tincSubnet   string
+	tincPort     int32
+	config       TincConfig
+
+	peersMu        sync.RWMutex
+	peers          map[string]PeerConfig
+	peerContainers map[string]ContainerID
+
+	kubeClient kubernetes.Interface
+
+	keys        *keystore.KeyPair
+	distributor keystore.Distributor
+	peerKeysMu  sync.RWMutex
+	peerKeys    map[string]ed25519.PublicKey
+}
+
+// podContainers tracks the containers that back a single pod: the tinc peer
+// the pod's workload containers share a network namespace with, and one
+// entry per container in pod.Spec.Containers, keyed by container name.
+type podContainers struct {
+	podName    string
+	peer       string
+	containers map[string]ContainerID
+}
+
+// PeerConfig describes one node of the tinc mesh: its own identity plus the
+// information every other peer needs to ConnectTo it.
+type PeerConfig struct {
+	Name           string `json:"name"`
+	PublicAddress  string `json:"publicAddress"`
+	PrivateAddress string `json:"privateAddress"`
+	Subnet         string `json:"subnet,omitempty"`
+	Port           int32  `json:"port,omitempty"`
+	PublicKey      string `json:"publicKey,omitempty"`
 }
 
 // TincConfig contains a tinc virtual-kubelet's configurable parameters.
 type TincConfig struct {
 	AutoConnect string `json:"autoconnect,omitempty"`
-	ConnectTo   string `json:"connect,omitempty"`
 	Device      string `json:"device,omitempty"`
 	DeviceType  string `json:"devicetype,omitempty"`
 	Mode        string `json:"mode,omitempty"`
 	Name        string `json:"name,omitempty"`
 
+	// Runtime selects the container engine backend (docker, podman or
+	// containerd). Defaults to DefaultRuntime.
+	Runtime string `json:"runtime,omitempty"`
+
+	// Peers is the mesh this node connects to. A pod joins one of them via
+	// the tincPeerAnnotation; ReconcilePeers adds/removes entries at
+	// runtime without restarting the provider.
+	Peers []PeerConfig `json:"peers,omitempty"`
+
+	// Keys configures this node's Ed25519 identity and how it exchanges
+	// public keys with its peers.
+	Keys KeyConfig `json:"keys,omitempty"`
+
+	// Store configures where pod state is persisted, so it survives a
+	// restart of the provider.
+	Store StoreConfig `json:"store,omitempty"`
+
 	CPU    string `json:"cpu,omitempty"`
 	Memory string `json:"memory,omitempty"`
 	Pods   string `json:"pods,omitempty"`
 }
 
+// KeyConfig controls Ed25519 key provisioning and distribution for a node.
+type KeyConfig struct {
+	// KeyDir is where this node's own keypair is persisted. Defaults to
+	// "<defaultKeyBaseDir>/<nodeName>".
+	KeyDir string `json:"keyDir,omitempty"`
+
+	// Distribution selects how peer public keys are exchanged:
+	// keyDistributionSecret (the default) or keyDistributionFile.
+	Distribution string `json:"distribution,omitempty"`
+
+	// SecretNamespace is where peer key Secrets live, in
+	// keyDistributionSecret mode.
+	SecretNamespace string `json:"secretNamespace,omitempty"`
+
+	// WatchDir is the directory watched for peer host files, in
+	// keyDistributionFile mode.
+	WatchDir string `json:"watchDir,omitempty"`
+}
+
 // NewTincProvider creates a new TincProvider
 func NewTincProvider(providerConfig, nodeName, tincAddress, tincSubnet string, tincPort int32) (*TincProvider, error) {
 	config, err := loadConfig(providerConfig, nodeName)
@@ -116,18 +204,102 @@ func NewTincProvider(providerConfig, nodeName, tincAddress, tincSubnet string, t
 		return nil, err
 	}
 
+	runtime, err := newRuntime(config.Runtime)
+	if err != nil {
+		return nil, fmt.Errorf("initializing tinc runtime backend: %v", err)
+	}
+
+	peers := make(map[string]PeerConfig, len(config.Peers))
+	for _, peer := range config.Peers {
+		peers[peer.Name] = peer
+	}
+
+	keys, err := keystore.EnsureKeyPair(config.Keys.KeyDir)
+	if err != nil {
+		return nil, fmt.Errorf("provisioning tinc keypair: %v", err)
+	}
+
+	kubeClient, err := newInClusterClient()
+	if err != nil {
+		log.Printf("tinc kube client unavailable, configMap/secret volumes and secret-mode key distribution will fail: %v", err)
+	}
+
+	distributor, err := newKeyDistributor(config.Keys, kubeClient)
+	if err != nil {
+		log.Printf("tinc key distribution disabled: %v", err)
+		distributor = nil
+	}
+
+	store, err := newPodStore(config.Store, nodeName)
+	if err != nil {
+		return nil, fmt.Errorf("initializing tinc pod store: %v", err)
+	}
+
 	provider := TincProvider{
-		nodeName:    nodeName,
-		tincAddress: tincAddress,
-		tincSubnet:  tincSubnet,
-		tincPort:    tincPort,
-		pods:        make(map[string]*v1.Pod),
-		config:      config,
+		nodeName:       nodeName,
+		store:          store,
+		tincAddress:    tincAddress,
+		tincSubnet:     tincSubnet,
+		tincPort:       tincPort,
+		containers:     make(map[string]*podContainers),
+		runtime:        runtime,
+		config:         config,
+		peers:          peers,
+		peerContainers: make(map[string]ContainerID),
+		kubeClient:     kubeClient,
+		keys:           keys,
+		distributor:    distributor,
+		peerKeys:       make(map[string]ed25519.PublicKey),
+	}
+
+	if err := provider.reconcileContainers(context.Background()); err != nil {
+		log.Printf("failed to reconcile containers from runtime: %v", err)
+	}
+
+	if distributor != nil {
+		if err := distributor.Publish(context.Background(), nodeName, keys.PublicKey); err != nil {
+			log.Printf("failed to publish tinc public key for %q: %v", nodeName, err)
+		}
 	}
 
 	return &provider, nil
 }
 
+// newInClusterClient builds a kubernetes client from the pod's in-cluster
+// config, for the provider's own calls back to the API server (reading
+// ConfigMaps/Secrets for volumes, publishing tinc public keys).
+func newInClusterClient() (kubernetes.Interface, error) {
+	restConfig, err := rest.InClusterConfig()
+	if err != nil {
+		return nil, fmt.Errorf("loading in-cluster config: %v", err)
+	}
+	client, err := kubernetes.NewForConfig(restConfig)
+	if err != nil {
+		return nil, fmt.Errorf("building kubernetes client: %v", err)
+	}
+	return client, nil
+}
+
+// newKeyDistributor builds the Distributor selected by cfg.Distribution.
+func newKeyDistributor(cfg KeyConfig, kubeClient kubernetes.Interface) (keystore.Distributor, error) {
+	switch cfg.Distribution {
+	case keyDistributionSecret:
+		if kubeClient == nil {
+			return nil, fmt.Errorf("keyDistributionSecret requires a working in-cluster kubernetes client")
+		}
+		return keystore.NewSecretDistributor(kubeClient, cfg.SecretNamespace, ""), nil
+
+	case keyDistributionFile:
+		if cfg.WatchDir == "" {
+			return nil, fmt.Errorf("keyDistributionFile requires watchDir to be set")
+		}
+		return keystore.NewFileDropDistributor(cfg.WatchDir)
+
+	default:
+		return nil, fmt.Errorf("unknown key distribution mode %q", cfg.Distribution)
+	}
+}
+
 // loadConfig loads the given json configuration files.
 
 func loadConfig(providerConfig, nodeName string) (config TincConfig, err error) {
@@ -145,9 +317,6 @@ func loadConfig(providerConfig, nodeName string) (config TincConfig, err error)
 		if config.AutoConnect == "" {
 			config.AutoConnect = tincAutoConnect
 		}
-		if config.ConnectTo == "" {
-			config.ConnectTo = DefaultTincRemotePeers
-		}
 		if config.Device == "" {
 			config.Device = tincDeviceTunTap
 		}
@@ -158,7 +327,43 @@ func loadConfig(providerConfig, nodeName string) (config TincConfig, err error)
 			config.Mode = tincModeSwitch
 		}
 		if config.Name == "" {
-			config.Name = tincMainName
+			config.Name = DefaultTincMainName
+		}
+		if config.Runtime == "" {
+			config.Runtime = DefaultRuntime
+		}
+		if len(config.Peers) == 0 {
+			config.Peers = []PeerConfig{
+				{
+					Name:           DefaultTincMainName,
+					PublicAddress:  DefaultTincMainAddress,
+					PrivateAddress: DefaultTincMainPrivateAddress,
+					Subnet:         DefaultTincSubnet,
+					Port:           DefaultTincPort,
+				},
+				{
+					Name:           DefaultTincRemotePeers,
+					PublicAddress:  DefaultTincPeerAddress,
+					PrivateAddress: DefaultTincPeerPrivateAddress,
+					Subnet:         DefaultTincSubnet,
+					Port:           DefaultTincPort,
+				},
+			}
+		}
+		if config.Keys.KeyDir == "" {
+			config.Keys.KeyDir = filepath.Join(defaultKeyBaseDir, nodeName)
+		}
+		if config.Keys.Distribution == "" {
+			config.Keys.Distribution = keyDistributionSecret
+		}
+		if config.Keys.SecretNamespace == "" {
+			config.Keys.SecretNamespace = defaultKeySecretNamespace
+		}
+		if config.Store.Backend == "" {
+			config.Store.Backend = podStoreBolt
+		}
+		if config.Store.Path == "" {
+			config.Store.Path = filepath.Join(config.Keys.KeyDir, defaultPodStorePath)
 		}
 		if config.CPU == "" {
 			config.CPU = defaultCPUCapacity
@@ -183,7 +388,9 @@ func loadConfig(providerConfig, nodeName string) (config TincConfig, err error)
 	return config, nil
 }
 
-// CreatePod accepts a Pod definition and stores it in memory.
+// CreatePod accepts a Pod definition, makes sure the tinc peer it selects is
+// up, then joins every container in pod.Spec.Containers to that peer's
+// network namespace so the pod is reachable over the tinc overlay.
 func (p *TincProvider) CreatePod(ctx context.Context, pod *v1.Pod) error {
 	log.Printf("receive CreatePod %q\n", pod.Name)
 
@@ -192,41 +399,434 @@ func (p *TincProvider) CreatePod(ctx context.Context, pod *v1.Pod) error {
 		return err
 	}
 
-	p.pods[key] = pod
+	peerName := pod.Annotations[tincPeerAnnotation]
+	if peerName == "" {
+		peerName = p.config.Name
+	}
 
-	if vpnMode := pod.Annotations["vpnmode"]; vpnMode == "peer" {
-		privateAddress = DefaultTincPeerPrivateAddress
-		tincMainName = DefaultTincRemotePeers
-		tincPeerName = DefaultTincMainName
-	} else {
-		privateAddress = DefaultTincMainPrivateAddress
-		tincMainName = DefaultTincMainName
-		tincPeerName = DefaultTincRemotePeers
+	if _, err := p.ensurePeer(ctx, peerName, podContainerPorts(pod)); err != nil {
+		return fmt.Errorf("failed to start tinc peer %q: %v", peerName, err)
+	}
+
+	pc := &podContainers{
+		podName:    pod.Name,
+		peer:       peerName,
+		containers: make(map[string]ContainerID, len(pod.Spec.Containers)),
 	}
 
-	tincStartupConfigHost = filepath.Join("/tmp", tincMainName, "vk-startup-config.conf")
-	tincMainConfigHost = filepath.Join("/tmp", tincMainName, "vk-main.conf")
-	tincUpScriptHost = filepath.Join("/tmp", tincMainName, "vk-tinc-up")
+	for _, container := range pod.Spec.Containers {
+		id, err := p.runPodContainer(ctx, pod, &container, peerName)
+		if err != nil {
+			p.teardownPodContainers(ctx, pc)
+			return fmt.Errorf("failed to start container %q: %v", container.Name, err)
+		}
+		pc.containers[container.Name] = id
+	}
 
-	if err := p.createStartupConfig(); err != nil {
-		return err
+	// Only persist the pod once its containers are actually up, so a
+	// failure here can't leak a pod into the store with no containers
+	// backing it; roll the containers back instead.
+	p.podsMu.Lock()
+	err = p.store.Put(ctx, key, pod)
+	p.podsMu.Unlock()
+	if err != nil {
+		p.teardownPodContainers(ctx, pc)
+		return fmt.Errorf("storing pod %q: %v", key, err)
+	}
+
+	p.containersMu.Lock()
+	p.containers[key] = pc
+	p.containersMu.Unlock()
+
+	return nil
+}
+
+// ensurePeer makes sure the named peer's tinc container is running, starting
+// it from the current mesh config the first time it's needed. Peers are
+// long-lived mesh infrastructure shared by every pod that joins them, so
+// they are never torn down by DeletePod.
+func (p *TincProvider) ensurePeer(ctx context.Context, name string, ports []PortMapping) (ContainerID, error) {
+	p.peersMu.Lock()
+	defer p.peersMu.Unlock()
+
+	if id, running := p.peerContainers[name]; running {
+		return id, nil
+	}
+
+	self, ok := p.peers[name]
+	if !ok {
+		return "", fmt.Errorf("peer %q is not configured", name)
 	}
 
-	_, _ = exec.Command(dockerClient, "rm", "--force", tincMainName).Output()
+	p.fetchMissingPeerKeys(ctx, p.peerNamesLocked())
+
+	paths := tincPathsFor(name)
+	if err := p.createStartupConfig(self, p.peerList(), paths); err != nil {
+		return "", err
+	}
 
-	out, err := exec.Command(dockerClient, "run", "--privileged", "--name="+tincMainName,
-		"--detach", "--rm",
-		fmt.Sprintf("--volume=%s:%s", tincStartupConfigHost, tincStartupConfigContainer),
-		fmt.Sprintf("--volume=%s:%s", tincMainConfigHost, tincMainConfigContainer),
-		fmt.Sprintf("--volume=%s:%s", tincUpScriptHost, tincUpScriptContainer),
-		tincImageName).CombinedOutput()
+	_ = p.runtime.Remove(ctx, ContainerID(name))
+
+	id, err := p.runtime.Run(ctx, RunSpec{
+		Name:   name,
+		Image:  tincImageName,
+		Detach: true,
+		Labels: map[string]string{peerLabel: name},
+		Security: SecurityOptions{
+			Privileged: true,
+		},
+		Volumes: []VolumeMount{
+			{HostPath: paths.startupConfigHost, ContainerPath: tincStartupConfigContainer},
+			{HostPath: paths.mainConfigHost, ContainerPath: tincMainConfigContainer},
+			{HostPath: paths.upScriptHost, ContainerPath: tincUpScriptContainer},
+		},
+		Ports: ports,
+	})
 	if err != nil {
-		return fmt.Errorf("failed to run docker-run:\nout: %s\nerr: %v\n", string(out), err)
+		return "", fmt.Errorf("failed to start tinc container: %v", err)
+	}
+
+	p.peerContainers[name] = id
+
+	return id, nil
+}
+
+// peerList returns the current mesh as a slice. Callers must hold peersMu.
+func (p *TincProvider) peerList() []PeerConfig {
+	peers := make([]PeerConfig, 0, len(p.peers))
+	for _, peer := range p.peers {
+		peers = append(peers, peer)
+	}
+	return peers
+}
+
+// peerNamesLocked returns the names of every peer other than this node.
+// Callers must hold peersMu.
+func (p *TincProvider) peerNamesLocked() []string {
+	names := make([]string, 0, len(p.peers))
+	for name := range p.peers {
+		if name == p.config.Name {
+			continue
+		}
+		names = append(names, name)
+	}
+	return names
+}
+
+// fetchMissingPeerKeys asks the configured Distributor for the public key of
+// every named peer that this node hasn't already got a key for, either from
+// static PeerConfig.PublicKey or a previous fetch. Callers must already hold
+// peersMu, since createStartupConfig reads peer.PublicKey under that lock;
+// peerKeysMu is acquired independently to avoid a lock-ordering dependency.
+func (p *TincProvider) fetchMissingPeerKeys(ctx context.Context, names []string) {
+	if p.distributor == nil {
+		return
+	}
+
+	for _, name := range names {
+		if p.peers[name].PublicKey != "" {
+			continue
+		}
+
+		p.peerKeysMu.RLock()
+		_, known := p.peerKeys[name]
+		p.peerKeysMu.RUnlock()
+		if known {
+			continue
+		}
+
+		pub, ok, err := p.distributor.Fetch(ctx, name)
+		if err != nil {
+			log.Printf("failed to fetch tinc public key for peer %q: %v", name, err)
+			continue
+		}
+		if !ok {
+			continue
+		}
+
+		p.peerKeysMu.Lock()
+		p.peerKeys[name] = pub
+		p.peerKeysMu.Unlock()
+	}
+}
+
+// peerPublicKey returns the base64 Ed25519 public key to advertise for peer,
+// preferring a statically configured key over one fetched at runtime.
+func (p *TincProvider) peerPublicKey(peer PeerConfig) string {
+	if peer.PublicKey != "" {
+		return peer.PublicKey
+	}
+
+	p.peerKeysMu.RLock()
+	defer p.peerKeysMu.RUnlock()
+
+	if pub, ok := p.peerKeys[peer.Name]; ok {
+		return keystore.PublicKeyBase64(pub)
+	}
+
+	return ""
+}
+
+// tincPaths are the host-side files a single peer's tinc container mounts.
+type tincPaths struct {
+	startupConfigHost string
+	mainConfigHost    string
+	upScriptHost      string
+}
+
+// tincPathsFor returns the host paths used to configure a given peer's tinc
+// container.
+func tincPathsFor(peerName string) tincPaths {
+	return tincPaths{
+		startupConfigHost: filepath.Join("/tmp", peerName, "vk-startup-config.conf"),
+		mainConfigHost:    filepath.Join("/tmp", peerName, "vk-main.conf"),
+		upScriptHost:      filepath.Join("/tmp", peerName, "vk-tinc-up"),
+	}
+}
+
+// ReconcilePeers adds or removes peers from the mesh at runtime: host files
+// for every still-running peer are rewritten and tinc is asked to reload,
+// so ConnectTo changes take effect without restarting any container. Pods
+// already joined to a removed peer are left running; it is up to the caller
+// to also repoint or delete them.
+func (p *TincProvider) ReconcilePeers(ctx context.Context, peers []PeerConfig) error {
+	p.peersMu.Lock()
+	defer p.peersMu.Unlock()
+
+	next := make(map[string]PeerConfig, len(peers))
+	for _, peer := range peers {
+		next[peer.Name] = peer
+	}
+
+	for name := range p.peers {
+		if _, keep := next[name]; keep {
+			continue
+		}
+		delete(p.peers, name)
+		if id, running := p.peerContainers[name]; running {
+			if err := p.runtime.Remove(ctx, id); err != nil {
+				log.Printf("failed to remove tinc peer %q: %v", name, err)
+			}
+			delete(p.peerContainers, name)
+		}
+	}
+
+	p.peers = next
+
+	p.fetchMissingPeerKeys(ctx, p.peerNamesLocked())
+
+	peerSlice := p.peerList()
+	for name, id := range p.peerContainers {
+		self, ok := p.peers[name]
+		if !ok {
+			continue
+		}
+
+		paths := tincPathsFor(name)
+		if err := p.createStartupConfig(self, peerSlice, paths); err != nil {
+			log.Printf("failed to refresh tinc config for peer %q: %v", name, err)
+			continue
+		}
+
+		if err := p.runtime.Exec(ctx, id, ExecSpec{Command: []string{"tinc", "reload"}}); err != nil {
+			log.Printf("failed to reload tinc peer %q: %v", name, err)
+		}
 	}
 
 	return nil
 }
 
+// runPodContainer starts a single container from pod.Spec.Containers joined
+// to the given peer's tinc network namespace, and returns its container ID.
+func (p *TincProvider) runPodContainer(ctx context.Context, pod *v1.Pod, container *v1.Container, peerName string) (ContainerID, error) {
+	name := peerName + "-" + container.Name
+
+	_ = p.runtime.Remove(ctx, ContainerID(name))
+
+	volumes, err := p.volumesForContainer(ctx, pod, container)
+	if err != nil {
+		return "", err
+	}
+
+	env := make(map[string]string, len(container.Env))
+	for _, e := range container.Env {
+		env[e.Name] = e.Value
+	}
+
+	spec := RunSpec{
+		Name:        name,
+		Image:       container.Image,
+		Command:     container.Command,
+		Args:        container.Args,
+		Env:         env,
+		WorkingDir:  container.WorkingDir,
+		NetworkMode: "container:" + peerName,
+		Labels: map[string]string{
+			podLabel:     pod.Namespace + "/" + pod.Name,
+			podPeerLabel: peerName,
+		},
+		Volumes:   volumes,
+		Resources: resourceLimits(container.Resources),
+		Security:  securityOptions(container.SecurityContext),
+		Detach:    true,
+	}
+
+	return p.runtime.Run(ctx, spec)
+}
+
+// podContainerPorts collects every port declared across a pod's containers.
+// They are published on the tinc peer's own container, which owns the
+// network namespace the workload containers join, rather than on the
+// workload containers themselves, since NetworkMode "container:<peer>"
+// inherits whatever the peer already publishes. Ports are only published
+// when ensurePeer first starts the peer's container; a pod that joins a peer
+// that is already running does not get its ports published.
+func podContainerPorts(pod *v1.Pod) []PortMapping {
+	var ports []PortMapping
+	for _, container := range pod.Spec.Containers {
+		for _, p := range container.Ports {
+			ports = append(ports, PortMapping{
+				HostPort:      p.HostPort,
+				ContainerPort: p.ContainerPort,
+				Protocol:      string(p.Protocol),
+			})
+		}
+	}
+	return ports
+}
+
+// resourceLimits translates a container's resource limits into RunSpec form.
+func resourceLimits(req v1.ResourceRequirements) ResourceLimits {
+	var limits ResourceLimits
+
+	if cpu, ok := req.Limits[v1.ResourceCPU]; ok {
+		limits.CPUs = cpu.AsDec().String()
+	}
+	if mem, ok := req.Limits[v1.ResourceMemory]; ok {
+		limits.MemoryBytes = mem.Value()
+	}
+
+	return limits
+}
+
+// securityOptions translates a container's SecurityContext into RunSpec form.
+func securityOptions(sc *v1.SecurityContext) SecurityOptions {
+	var opts SecurityOptions
+	if sc == nil {
+		return opts
+	}
+
+	if sc.Privileged != nil {
+		opts.Privileged = *sc.Privileged
+	}
+	if sc.RunAsUser != nil {
+		opts.User = fmt.Sprintf("%d", *sc.RunAsUser)
+	}
+	if sc.ReadOnlyRootFilesystem != nil {
+		opts.ReadOnlyRootFS = *sc.ReadOnlyRootFilesystem
+	}
+	if sc.Capabilities != nil {
+		for _, c := range sc.Capabilities.Add {
+			opts.CapAdd = append(opts.CapAdd, string(c))
+		}
+		for _, c := range sc.Capabilities.Drop {
+			opts.CapDrop = append(opts.CapDrop, string(c))
+		}
+	}
+
+	return opts
+}
+
+// volumesForContainer resolves a container's volumeMounts against
+// pod.Spec.Volumes. emptyDir and hostPath are mapped directly; configMap and
+// secret volumes are materialized to a temporary directory first, since the
+// runtime backends have no native equivalent.
+func (p *TincProvider) volumesForContainer(ctx context.Context, pod *v1.Pod, container *v1.Container) ([]VolumeMount, error) {
+	volumes := make(map[string]v1.Volume, len(pod.Spec.Volumes))
+	for _, v := range pod.Spec.Volumes {
+		volumes[v.Name] = v
+	}
+
+	var mounts []VolumeMount
+
+	for _, mount := range container.VolumeMounts {
+		volume, ok := volumes[mount.Name]
+		if !ok {
+			return nil, fmt.Errorf("volume %q referenced by container %q not found in pod spec", mount.Name, container.Name)
+		}
+
+		hostPath, err := p.materializeVolume(ctx, pod, volume)
+		if err != nil {
+			return nil, err
+		}
+
+		mounts = append(mounts, VolumeMount{HostPath: hostPath, ContainerPath: mount.MountPath, ReadOnly: mount.ReadOnly})
+	}
+
+	return mounts, nil
+}
+
+// materializeVolume returns a host path backing the given pod volume.
+func (p *TincProvider) materializeVolume(ctx context.Context, pod *v1.Pod, volume v1.Volume) (string, error) {
+	switch {
+	case volume.EmptyDir != nil:
+		dir := filepath.Join("/tmp", "vk-volumes", pod.Namespace+"-"+pod.Name, volume.Name)
+		if err := os.MkdirAll(dir, os.FileMode(0775)); err != nil {
+			return "", err
+		}
+		return dir, nil
+
+	case volume.HostPath != nil:
+		return volume.HostPath.Path, nil
+
+	case volume.ConfigMap != nil:
+		dir := filepath.Join("/tmp", "vk-volumes", pod.Namespace+"-"+pod.Name, volume.Name)
+		if err := os.MkdirAll(dir, os.FileMode(0775)); err != nil {
+			return "", err
+		}
+		if p.kubeClient == nil {
+			return "", fmt.Errorf("materializing configMap volume %q: no kubernetes client available", volume.Name)
+		}
+		cm, err := p.kubeClient.CoreV1().ConfigMaps(pod.Namespace).Get(ctx, volume.ConfigMap.Name, metav1.GetOptions{})
+		if err != nil {
+			return "", fmt.Errorf("fetching configMap %q for volume %q: %v", volume.ConfigMap.Name, volume.Name, err)
+		}
+		for k, v := range cm.Data {
+			if err := ioutil.WriteFile(filepath.Join(dir, k), []byte(v), os.FileMode(0644)); err != nil {
+				return "", fmt.Errorf("writing configMap %q key %q: %v", volume.ConfigMap.Name, k, err)
+			}
+		}
+		for k, v := range cm.BinaryData {
+			if err := ioutil.WriteFile(filepath.Join(dir, k), v, os.FileMode(0644)); err != nil {
+				return "", fmt.Errorf("writing configMap %q key %q: %v", volume.ConfigMap.Name, k, err)
+			}
+		}
+		return dir, nil
+
+	case volume.Secret != nil:
+		dir := filepath.Join("/tmp", "vk-volumes", pod.Namespace+"-"+pod.Name, volume.Name)
+		if err := os.MkdirAll(dir, os.FileMode(0775)); err != nil {
+			return "", err
+		}
+		if p.kubeClient == nil {
+			return "", fmt.Errorf("materializing secret volume %q: no kubernetes client available", volume.Name)
+		}
+		secret, err := p.kubeClient.CoreV1().Secrets(pod.Namespace).Get(ctx, volume.Secret.SecretName, metav1.GetOptions{})
+		if err != nil {
+			return "", fmt.Errorf("fetching secret %q for volume %q: %v", volume.Secret.SecretName, volume.Name, err)
+		}
+		for k, v := range secret.Data {
+			if err := ioutil.WriteFile(filepath.Join(dir, k), v, os.FileMode(0600)); err != nil {
+				return "", fmt.Errorf("writing secret %q key %q: %v", volume.Secret.SecretName, k, err)
+			}
+		}
+		return dir, nil
+
+	default:
+		return "", fmt.Errorf("unsupported volume source for volume %q", volume.Name)
+	}
+}
+
 // UpdatePod accepts a Pod definition and updates its reference.
 func (p *TincProvider) UpdatePod(ctx context.Context, pod *v1.Pod) error {
 	log.Printf("receive UpdatePod %q\n", pod.Name)
@@ -236,12 +836,13 @@ func (p *TincProvider) UpdatePod(ctx context.Context, pod *v1.Pod) error {
 		return err
 	}
 
-	p.pods[key] = pod
+	p.podsMu.Lock()
+	defer p.podsMu.Unlock()
 
-	return nil
+	return p.store.Put(ctx, key, pod)
 }
 
-// DeletePod deletes the specified pod out of memory.
+// DeletePod deletes the specified pod along with every container it started.
 func (p *TincProvider) DeletePod(ctx context.Context, pod *v1.Pod) (err error) {
 	log.Printf("receive DeletePod %q\n", pod.Name)
 
@@ -250,23 +851,47 @@ func (p *TincProvider) DeletePod(ctx context.Context, pod *v1.Pod) (err error) {
 		return err
 	}
 
-	if _, exists := p.pods[key]; !exists {
+	p.podsMu.Lock()
+	_, exists, err := p.store.Get(ctx, key)
+	if err != nil {
+		p.podsMu.Unlock()
+		return fmt.Errorf("looking up pod %q: %v", key, err)
+	}
+	if !exists {
+		p.podsMu.Unlock()
 		return strongerrors.NotFound(fmt.Errorf("pod not found"))
 	}
+	err = p.store.Delete(ctx, key)
+	p.podsMu.Unlock()
+	if err != nil {
+		return fmt.Errorf("deleting pod %q: %v", key, err)
+	}
 
-	delete(p.pods, key)
-
-	fmt.Printf("running docker-rm %s\n", tincMainName)
+	p.containersMu.Lock()
+	pc, exists := p.containers[key]
+	delete(p.containers, key)
+	p.containersMu.Unlock()
 
-	out, err := exec.Command(dockerClient, "rm", "--force", p.config.Name).CombinedOutput()
-	if err != nil {
-		return fmt.Errorf("failed to run docker-rm:\nout: %s\nerr: %v\n", string(out), err)
+	if exists {
+		p.teardownPodContainers(ctx, pc)
 	}
 
 	return nil
 }
 
-// GetPod returns a pod by name that is stored in memory.
+// teardownPodContainers removes all of a pod's own containers, logging
+// rather than aborting on individual failures so that one stuck container
+// doesn't leave the rest behind. The tinc peer the pod joined is left
+// running, since other pods may still be using it.
+func (p *TincProvider) teardownPodContainers(ctx context.Context, pc *podContainers) {
+	for name, id := range pc.containers {
+		if err := p.runtime.Remove(ctx, id); err != nil {
+			log.Printf("failed to remove container %q (%s): %v", name, id, err)
+		}
+	}
+}
+
+// GetPod returns a pod by name from the pod store.
 func (p *TincProvider) GetPod(ctx context.Context, namespace, name string) (pod *v1.Pod, err error) {
 	log.Printf("receive GetPod %q\n", name)
 
@@ -275,16 +900,46 @@ func (p *TincProvider) GetPod(ctx context.Context, namespace, name string) (pod
 		return nil, err
 	}
 
-	if pod, ok := p.pods[key]; ok {
-		return pod, nil
+	p.podsMu.RLock()
+	pod, ok, err := p.store.Get(ctx, key)
+	p.podsMu.RUnlock()
+	if err != nil {
+		return nil, fmt.Errorf("getting pod %q: %v", key, err)
+	}
+	if !ok {
+		return nil, strongerrors.NotFound(fmt.Errorf("pod \"%s/%s\" is not known to the provider", namespace, name))
 	}
-	return nil, strongerrors.NotFound(fmt.Errorf("pod \"%s/%s\" is not known to the provider", namespace, name))
+
+	return pod, nil
 }
 
 // GetContainerLogs retrieves the logs of a container by name from the provider.
-func (p *TincProvider) GetContainerLogs(ctx context.Context, namespace, podName, containerName string, tail int) (string, error) {
+func (p *TincProvider) GetContainerLogs(ctx context.Context, namespace, podName, containerName string, opts providers.ContainerLogOpts) (string, error) {
 	log.Printf("receive GetContainerLogs %q\n", podName)
-	return "", nil
+
+	id, err := p.containerID(namespace, podName, containerName)
+	if err != nil {
+		return "", err
+	}
+
+	rc, err := p.runtime.Logs(ctx, id, LogOptions{
+		Tail:         opts.Tail,
+		Follow:       opts.Follow,
+		SinceSeconds: opts.SinceSeconds,
+		Timestamps:   opts.Timestamps,
+		Previous:     opts.Previous,
+	})
+	if err != nil {
+		return "", fmt.Errorf("getting logs for container %q: %v", containerName, err)
+	}
+	defer rc.Close()
+
+	out, err := ioutil.ReadAll(rc)
+	if err != nil {
+		return "", fmt.Errorf("reading logs for container %q: %v", containerName, err)
+	}
+
+	return string(out), nil
 }
 
 // GetPodFullName is full pod name as defined in the provider context
@@ -294,9 +949,93 @@ func (p *TincProvider) GetPodFullName(namespace string, pod string) string {
 
 // ExecInContainer executes a command in a container in the pod, copying data
 // between in/out/err and the container's stdin/stdout/stderr.
-func (p *TincProvider) ExecInContainer(name string, uid types.UID, container string, cmd []string, in io.Reader, out, err io.WriteCloser, tty bool, resize <-chan remotecommand.TerminalSize, timeout time.Duration) error {
+func (p *TincProvider) ExecInContainer(name string, uid types.UID, container string, cmd []string, in io.Reader, out, errW io.WriteCloser, tty bool, resize <-chan remotecommand.TerminalSize, timeout time.Duration) error {
 	log.Printf("receive ExecInContainer %q\n", container)
-	return nil
+
+	id, ok := p.findContainerByName(name, container)
+	if !ok {
+		return strongerrors.NotFound(fmt.Errorf("container %q is not known to the provider", container))
+	}
+
+	ctx := context.Background()
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+
+	return p.runtime.Exec(ctx, id, ExecSpec{
+		Command: cmd,
+		Stdin:   in,
+		Stdout:  out,
+		Stderr:  errW,
+		TTY:     tty,
+		Resize:  convertResizeChan(resize),
+	})
+}
+
+// containerID resolves a pod/container name to its tracked runtime container ID.
+func (p *TincProvider) containerID(namespace, podName, containerName string) (ContainerID, error) {
+	key, err := buildKeyFromNames(namespace, podName)
+	if err != nil {
+		return "", err
+	}
+
+	p.containersMu.RLock()
+	defer p.containersMu.RUnlock()
+
+	pc, ok := p.containers[key]
+	if !ok {
+		return "", strongerrors.NotFound(fmt.Errorf("pod \"%s/%s\" is not known to the provider", namespace, podName))
+	}
+
+	id, ok := pc.containers[containerName]
+	if !ok {
+		return "", strongerrors.NotFound(fmt.Errorf("container %q is not known to the provider", containerName))
+	}
+
+	return id, nil
+}
+
+// findContainerByName looks up a tracked container ID by pod name and
+// container name, for call sites like ExecInContainer that are only given a
+// pod name by the virtual-kubelet interface and not its namespace. Scoping
+// by podName (rather than scanning every pod's containers for the first
+// match) keeps two differently named pods that share a container name (e.g.
+// "web") from execing into each other. Pods with the same name in different
+// namespaces cannot be told apart here; the first match wins.
+func (p *TincProvider) findContainerByName(podName, containerName string) (ContainerID, bool) {
+	p.containersMu.RLock()
+	defer p.containersMu.RUnlock()
+
+	for _, pc := range p.containers {
+		if pc.podName != podName {
+			continue
+		}
+		if id, ok := pc.containers[containerName]; ok {
+			return id, true
+		}
+	}
+	return "", false
+}
+
+// convertResizeChan adapts a remotecommand.TerminalSize channel to the
+// runtime package's own TerminalSize, so this package doesn't have to
+// import client-go types into runtime.go.
+func convertResizeChan(resize <-chan remotecommand.TerminalSize) <-chan TerminalSize {
+	if resize == nil {
+		return nil
+	}
+
+	out := make(chan TerminalSize)
+	go func() {
+		defer close(out)
+		for size := range resize {
+			out <- TerminalSize{Width: size.Width, Height: size.Height}
+		}
+	}()
+
+	return out
 }
 
 // GetPodStatus returns the status of a pod by name that is "running".
@@ -332,34 +1071,159 @@ func (p *TincProvider) GetPodStatus(ctx context.Context, namespace, name string)
 		return nil, err
 	}
 
+	key, err := buildKeyFromNames(namespace, name)
+	if err != nil {
+		return nil, err
+	}
+
+	p.containersMu.RLock()
+	pc, tracked := p.containers[key]
+	p.containersMu.RUnlock()
+
+	if tracked {
+		p.peersMu.RLock()
+		if peer, ok := p.peers[pc.peer]; ok {
+			status.PodIP = peer.PrivateAddress
+		}
+		p.peersMu.RUnlock()
+	}
+
 	for _, container := range pod.Spec.Containers {
-		status.ContainerStatuses = append(status.ContainerStatuses, v1.ContainerStatus{
-			Name:         container.Name,
-			Image:        container.Image,
-			Ready:        true,
-			RestartCount: 0,
-			State: v1.ContainerState{
-				Running: &v1.ContainerStateRunning{
-					StartedAt: now,
+		containerStatus := v1.ContainerStatus{
+			Name:  container.Name,
+			Image: container.Image,
+		}
+
+		var id ContainerID
+		var ok bool
+		if tracked {
+			p.containersMu.RLock()
+			id, ok = pc.containers[container.Name]
+			p.containersMu.RUnlock()
+		}
+
+		if !ok {
+			containerStatus.State = v1.ContainerState{Waiting: &v1.ContainerStateWaiting{Reason: "ContainerCreating"}}
+			status.ContainerStatuses = append(status.ContainerStatuses, containerStatus)
+			continue
+		}
+
+		info, err := p.runtime.Inspect(ctx, id)
+		if err != nil {
+			containerStatus.State = v1.ContainerState{Waiting: &v1.ContainerStateWaiting{Reason: "Unknown", Message: err.Error()}}
+			status.ContainerStatuses = append(status.ContainerStatuses, containerStatus)
+			continue
+		}
+
+		containerStatus.Ready = info.Running
+		containerStatus.RestartCount = info.RestartCount
+		if info.Running {
+			containerStatus.State = v1.ContainerState{Running: &v1.ContainerStateRunning{StartedAt: now}}
+		} else {
+			containerStatus.State = v1.ContainerState{
+				Terminated: &v1.ContainerStateTerminated{
+					ExitCode: int32(info.ExitCode),
+					Reason:   info.Status,
 				},
-			},
-		})
+			}
+		}
+
+		status.ContainerStatuses = append(status.ContainerStatuses, containerStatus)
 	}
 
 	return status, nil
 }
 
-// GetPods returns a list of all pods known to be "running".
+// GetPods returns a list of all pods known to be "running". Before returning,
+// it reconciles the in-memory container tracking against what the runtime
+// backend actually has running, labeled with podLabel, so that a provider
+// restart doesn't leave CreatePod-started containers orphaned from
+// DeletePod/GetPodStatus's view of the world.
 func (p *TincProvider) GetPods(ctx context.Context) ([]*v1.Pod, error) {
 	log.Printf("receive GetPods\n")
 
-	var pods []*v1.Pod
+	if err := p.reconcileContainers(ctx); err != nil {
+		log.Printf("failed to reconcile containers from runtime: %v", err)
+	}
+
+	p.podsMu.RLock()
+	defer p.podsMu.RUnlock()
+
+	return p.store.List(ctx)
+}
+
+// reconcileContainers lists containers labeled with podLabel and makes sure
+// every pod this provider already knows about has its containers map
+// populated, so state converges with what is actually running after a
+// restart of the virtual-kubelet process.
+func (p *TincProvider) reconcileContainers(ctx context.Context) error {
+	infos, err := p.runtime.List(ctx, podLabel, "")
+	if err != nil {
+		return fmt.Errorf("listing runtime containers: %v", err)
+	}
+
+	for _, info := range infos {
+		namespacedName := info.Labels[podLabel]
+		parts := strings.SplitN(namespacedName, "/", 2)
+		if len(parts) != 2 {
+			continue
+		}
+
+		podKey, err := buildKeyFromNames(parts[0], parts[1])
+		if err != nil {
+			continue
+		}
+
+		p.podsMu.RLock()
+		_, known, err := p.store.Get(ctx, podKey)
+		p.podsMu.RUnlock()
+		if err != nil || !known {
+			continue
+		}
+
+		peerName := info.Labels[podPeerLabel]
+
+		containerName := strings.TrimPrefix(info.Name, "/"+peerName+"-")
+		containerName = strings.TrimPrefix(containerName, peerName+"-")
+
+		p.containersMu.Lock()
+		pc, exists := p.containers[podKey]
+		if !exists {
+			pc = &podContainers{podName: parts[1], peer: peerName, containers: make(map[string]ContainerID)}
+			p.containers[podKey] = pc
+		}
+		if _, tracked := pc.containers[containerName]; !tracked {
+			pc.containers[containerName] = info.ID
+		}
+		p.containersMu.Unlock()
+	}
+
+	return p.reconcilePeerContainers(ctx)
+}
 
-	for _, pod := range p.pods {
-		pods = append(pods, pod)
+// reconcilePeerContainers rediscovers already-running peer containers after
+// a restart, so ensurePeer doesn't start a second tinc node for a peer that
+// is already up.
+func (p *TincProvider) reconcilePeerContainers(ctx context.Context) error {
+	infos, err := p.runtime.List(ctx, peerLabel, "")
+	if err != nil {
+		return fmt.Errorf("listing runtime peer containers: %v", err)
 	}
 
-	return pods, nil
+	p.peersMu.Lock()
+	defer p.peersMu.Unlock()
+
+	for _, info := range infos {
+		name := info.Labels[peerLabel]
+		if _, known := p.peers[name]; !known {
+			continue
+		}
+		if _, tracked := p.peerContainers[name]; !tracked {
+			p.peerContainers[name] = info.ID
+		}
+	}
+
+	return nil
 }
 
 // Capacity returns a resource list containing the capacity limits.
@@ -375,6 +1239,22 @@ func (p *TincProvider) Capacity(ctx context.Context) v1.ResourceList {
 // NodeConditions returns a list of conditions (Ready, OutOfDisk, etc), for updates to the node status
 // within Kubernetes.
 func (p *TincProvider) NodeConditions(ctx context.Context) []v1.NodeCondition {
+	networkCondition := v1.NodeCondition{
+		Type:               "NetworkUnavailable",
+		LastHeartbeatTime:  metav1.Now(),
+		LastTransitionTime: metav1.Now(),
+	}
+
+	if p.keysExchanged() {
+		networkCondition.Status = v1.ConditionFalse
+		networkCondition.Reason = "RouteCreated"
+		networkCondition.Message = "RouteController created a route"
+	} else {
+		networkCondition.Status = v1.ConditionTrue
+		networkCondition.Reason = "KeysNotExchanged"
+		networkCondition.Message = "waiting for at least one tinc peer's public key to be exchanged"
+	}
+
 	return []v1.NodeCondition{
 		{
 			Type:               "Ready",
@@ -408,18 +1288,33 @@ func (p *TincProvider) NodeConditions(ctx context.Context) []v1.NodeCondition {
 			Reason:             "KubeletHasNoDiskPressure",
 			Message:            "kubelet has no disk pressure",
 		},
-		{
-			Type:               "NetworkUnavailable",
-			Status:             v1.ConditionFalse,
-			LastHeartbeatTime:  metav1.Now(),
-			LastTransitionTime: metav1.Now(),
-			Reason:             "RouteCreated",
-			Message:            "RouteController created a route",
-		},
+		networkCondition,
 	}
 
 }
 
+// keysExchanged reports whether at least one configured peer (other than
+// this node) has a known public key, either statically configured or
+// fetched by the key Distributor. A mesh with no other peers yet is
+// trivially satisfied.
+func (p *TincProvider) keysExchanged() bool {
+	p.peersMu.RLock()
+	defer p.peersMu.RUnlock()
+
+	hasOtherPeers := false
+	for name, peer := range p.peers {
+		if name == p.config.Name {
+			continue
+		}
+		hasOtherPeers = true
+		if p.peerPublicKey(peer) != "" {
+			return true
+		}
+	}
+
+	return !hasOtherPeers
+}
+
 // NodeAddresses returns a list of addresses for the node status
 // within Kubernetes.
 func (p *TincProvider) NodeAddresses(ctx context.Context) []v1.NodeAddress {
@@ -447,64 +1342,176 @@ func (p *TincProvider) OperatingSystem() string {
 	return providers.OperatingSystemLinux
 }
 
-// GetStatsSummary returns dummy stats for all pods known by this provider.
+// GetStatsSummary returns resource usage stats, pulled from the runtime
+// backend, for every pod known by this provider plus an aggregate for the
+// whole virtual node.
 func (p *TincProvider) GetStatsSummary(ctx context.Context) (*stats.Summary, error) {
-	// Return the dummy stats.
-	return &stats.Summary{}, nil
+	now := metav1.NewTime(time.Now())
+
+	summary := &stats.Summary{
+		Node: stats.NodeStats{
+			NodeName:  p.nodeName,
+			StartTime: now,
+		},
+	}
+
+	var nodeCPU, nodeMemUsage, nodeMemWorkingSet, nodeRx, nodeTx uint64
+
+	p.podsMu.RLock()
+	pods, err := p.store.List(ctx)
+	p.podsMu.RUnlock()
+	if err != nil {
+		return nil, fmt.Errorf("listing pods: %v", err)
+	}
+
+	for _, pod := range pods {
+		key, err := buildKey(pod)
+		if err != nil {
+			continue
+		}
+
+		p.containersMu.RLock()
+		pc, tracked := p.containers[key]
+		p.containersMu.RUnlock()
+		if !tracked {
+			continue
+		}
+
+		podStats := stats.PodStats{
+			PodRef: stats.PodReference{
+				Name:      pod.Name,
+				Namespace: pod.Namespace,
+				UID:       string(pod.UID),
+			},
+			StartTime: now,
+		}
+
+		var podCPU, podMemUsage, podMemWorkingSet, podRx, podTx uint64
+
+		for _, container := range pod.Spec.Containers {
+			p.containersMu.RLock()
+			id, ok := pc.containers[container.Name]
+			p.containersMu.RUnlock()
+			if !ok {
+				continue
+			}
+
+			containerStats, err := p.runtime.Stats(ctx, id)
+			if err != nil {
+				log.Printf("failed to get stats for container %q: %v", container.Name, err)
+				continue
+			}
+
+			cpu := containerStats.CPUUsageNanoCores
+			memUsage := containerStats.MemoryUsageBytes
+			memWorkingSet := containerStats.MemoryWorkingSetBytes
+
+			podStats.Containers = append(podStats.Containers, stats.ContainerStats{
+				Name:      container.Name,
+				StartTime: now,
+				CPU:       &stats.CPUStats{Time: now, UsageNanoCores: &cpu},
+				Memory:    &stats.MemoryStats{Time: now, UsageBytes: &memUsage, WorkingSetBytes: &memWorkingSet},
+			})
+
+			podCPU += containerStats.CPUUsageNanoCores
+			podMemUsage += containerStats.MemoryUsageBytes
+			podMemWorkingSet += containerStats.MemoryWorkingSetBytes
+			podRx += containerStats.NetworkRxBytes
+			podTx += containerStats.NetworkTxBytes
+		}
+
+		podStats.CPU = &stats.CPUStats{Time: now, UsageNanoCores: &podCPU}
+		podStats.Memory = &stats.MemoryStats{Time: now, UsageBytes: &podMemUsage, WorkingSetBytes: &podMemWorkingSet}
+		podStats.Network = &stats.NetworkStats{
+			Time:           now,
+			InterfaceStats: stats.InterfaceStats{Name: "tap0", RxBytes: &podRx, TxBytes: &podTx},
+		}
+
+		summary.Pods = append(summary.Pods, podStats)
+
+		nodeCPU += podCPU
+		nodeMemUsage += podMemUsage
+		nodeMemWorkingSet += podMemWorkingSet
+		nodeRx += podRx
+		nodeTx += podTx
+	}
+
+	summary.Node.CPU = &stats.CPUStats{Time: now, UsageNanoCores: &nodeCPU}
+	summary.Node.Memory = &stats.MemoryStats{Time: now, UsageBytes: &nodeMemUsage, WorkingSetBytes: &nodeMemWorkingSet}
+	summary.Node.Network = &stats.NetworkStats{
+		Time:           now,
+		InterfaceStats: stats.InterfaceStats{Name: "tap0", RxBytes: &nodeRx, TxBytes: &nodeTx},
+	}
+
+	return summary, nil
 }
 
-// createStartupConfig accepts a Pod definition and stores it in memory.
-func (p *TincProvider) createStartupConfig() error {
+// createStartupConfig renders the tinc config for a single peer: one
+// ConnectTo line per other peer in the mesh, and one hosts/<Name> address
+// block per peer, self included, so the peer connects to everyone
+// ReconcilePeers currently knows about rather than a single hard-coded remote.
+func (p *TincProvider) createStartupConfig(self PeerConfig, peers []PeerConfig, paths tincPaths) error {
 	// /tmp/.../vk-startup-config.conf
 	data := fmt.Sprintf("add AutoConnect = %s\n", p.config.AutoConnect)
-	data += fmt.Sprintf("add ConnectTo = %s\n", tincPeerName)
+	for _, peer := range peers {
+		if peer.Name == self.Name {
+			continue
+		}
+		data += fmt.Sprintf("add ConnectTo = %s\n", peer.Name)
+	}
 	data += fmt.Sprintf("add Device = %s\n", p.config.Device)
 	data += fmt.Sprintf("add DeviceType = %s\n", p.config.DeviceType)
 	data += fmt.Sprintf("add Mode = %s\n", p.config.Mode)
-	data += fmt.Sprintf("add Name = %s\n", tincMainName)
-
-	nodeMain := p.config.Name
+	data += fmt.Sprintf("add Name = %s\n", self.Name)
 
-	data += fmt.Sprintf("add %s.Address = %s\n", nodeMain, myAddress)
-	data += fmt.Sprintf("add %s.Subnet = %s\n", nodeMain, p.tincSubnet)
-	data += fmt.Sprintf("add %s.Port = %d\n", nodeMain, p.tincPort)
-
-	if err := os.MkdirAll(filepath.Join("/tmp", tincMainName), os.FileMode(0775)); err != nil {
-		return err
+	for _, peer := range peers {
+		peerSubnet := peer.Subnet
+		if peerSubnet == "" {
+			peerSubnet = p.tincSubnet
+		}
+		peerPort := peer.Port
+		if peerPort == 0 {
+			peerPort = p.tincPort
+		}
+		data += fmt.Sprintf("add %s.Address = %s\n", peer.Name, peer.PublicAddress)
+		data += fmt.Sprintf("add %s.Subnet = %s\n", peer.Name, peerSubnet)
+		data += fmt.Sprintf("add %s.Port = %d\n", peer.Name, peerPort)
+		if key := p.peerPublicKey(peer); key != "" {
+			data += fmt.Sprintf("add %s.Ed25519PublicKey = %s\n", peer.Name, key)
+		}
 	}
 
-	nodePeers := strings.Fields(DefaultTincRemotePeers)
-
-	for _, nodePeer := range nodePeers {
-		data += fmt.Sprintf("add %s.Address = %s\n", nodePeer, peerAddress)
-		data += fmt.Sprintf("add %s.Subnet = %s\n", nodePeer, p.tincSubnet)
-		data += fmt.Sprintf("add %s.Port = %d\n", nodePeer, p.tincPort)
+	if err := os.MkdirAll(filepath.Join("/tmp", self.Name), os.FileMode(0775)); err != nil {
+		return err
 	}
 
-	if err := ioutil.WriteFile(tincStartupConfigHost, []byte(data), os.FileMode(0644)); err != nil {
+	if err := ioutil.WriteFile(paths.startupConfigHost, []byte(data), os.FileMode(0644)); err != nil {
 		return err
 	}
 
 	// /tmp/.../vk-main.conf
 	dataMain := fmt.Sprintf("AutoConnect = %s\n", p.config.AutoConnect)
-	dataMain += fmt.Sprintf("ConnectTo = %s\n", tincPeerName)
+	for _, peer := range peers {
+		if peer.Name == self.Name {
+			continue
+		}
+		dataMain += fmt.Sprintf("ConnectTo = %s\n", peer.Name)
+	}
 	dataMain += fmt.Sprintf("Device = %s\n", p.config.Device)
 	dataMain += fmt.Sprintf("DeviceType = %s\n", p.config.DeviceType)
-	dataMain += fmt.Sprintf("ExperimentalProtocol = %s\n", p.config.ExperimentalProtocol)
 	dataMain += fmt.Sprintf("Mode = %s\n", p.config.Mode)
-	dataMain += fmt.Sprintf("Name = %s\n", tincMainName)
+	dataMain += fmt.Sprintf("Name = %s\n", self.Name)
 
-	if err := ioutil.WriteFile(tincMainConfigHost, []byte(dataMain), os.FileMode(0644)); err != nil {
+	if err := ioutil.WriteFile(paths.mainConfigHost, []byte(dataMain), os.FileMode(0644)); err != nil {
 		return err
 	}
 
 	// /tmp/.../vk-tinc-up
-
 	dataScr := fmt.Sprintf("#!/bin/bash\n")
 	dataScr += fmt.Sprintf("ip link set tap0 up\n")
-	dataScr += fmt.Sprintf("ip addr add %s/24 dev tap0\n", privateAddress)
+	dataScr += fmt.Sprintf("ip addr add %s/24 dev tap0\n", self.PrivateAddress)
 
-	if err := ioutil.WriteFile(tincUpScriptHost, []byte(dataScr), os.FileMode(0755)); err != nil {
+	if err := ioutil.WriteFile(paths.upScriptHost, []byte(dataScr), os.FileMode(0755)); err != nil {
 		return err
 	}
 